@@ -0,0 +1,144 @@
+// 日志压测工具，按可配置的合成负载压测任意配置的 Logger，用于按机器评估队列与缓存参数
+//
+//	l := logger.NewLogger()
+//	l.SetOutput(io.Discard)
+//	l.Start()
+//	r := bench.Run(l, bench.Workload{Entries: 1000000, Concurrency: 8, MessageSize: 128, Fields: 4})
+//	fmt.Println(r)
+package bench
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whitewolfpipi/logger"
+)
+
+// 合成负载
+type Workload struct {
+	Entries     int            // 总条数，0 使用默认值 100000
+	Concurrency int            // 并发goroutine数，0 使用 1
+	MessageSize int            // 消息字节数，0 使用默认值 64
+	Fields      int            // 每条日志附加的字段数
+	Level       logger.LogType // 输出级别，零值为 DEBUG
+	Burst       int            // 突发负载：每个goroutine连续输出 Burst 条后暂停 BurstPause，0 表示持续输出
+	BurstPause  time.Duration  // 突发间隔
+}
+
+// 压测结果
+type Result struct {
+	Entries        int           // 输出条数
+	Duration       time.Duration // 全部输出并 Flush 完成的耗时
+	Throughput     float64       // 每秒条数
+	P50            time.Duration // 单次日志调用耗时的中位数
+	P99            time.Duration // 单次日志调用耗时的 99 分位
+	Max            time.Duration // 单次日志调用的最大耗时
+	AllocsPerEntry float64       // 每条日志的内存分配次数
+	BytesPerEntry  float64       // 每条日志的分配字节数
+	FlushErr       error         // 最后 Flush 的错误
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d entries in %s (%.0f/s) | latency p50=%s p99=%s max=%s | %.1f allocs/entry %.0f B/entry",
+		r.Entries, r.Duration.Round(time.Millisecond), r.Throughput, r.P50, r.P99, r.Max,
+		r.AllocsPerEntry, r.BytesPerEntry)
+}
+
+// 按负载压测日志，结束时 Flush 使全部日志写出
+func Run(l *logger.Logger, w Workload) Result {
+	if w.Entries <= 0 {
+		w.Entries = 100000
+	}
+	if w.Concurrency <= 0 {
+		w.Concurrency = 1
+	}
+	if w.MessageSize <= 0 {
+		w.MessageSize = 64
+	}
+
+	msg := strings.Repeat("x", w.MessageSize)
+	kvs := make([]interface{}, 0, w.Fields*2)
+	for i := 0; i < w.Fields; i++ {
+		kvs = append(kvs, "field"+strconv.Itoa(i), "value"+strconv.Itoa(i))
+	}
+	logf := levelFunc(l, w.Level)
+
+	// 每个goroutine记录各自的调用耗时，预先分配，不计入日志的分配
+	latencies := make([][]time.Duration, w.Concurrency)
+	for g := range latencies {
+		n := w.Entries / w.Concurrency
+		if g < w.Entries%w.Concurrency {
+			n++
+		}
+		latencies[g] = make([]time.Duration, 0, n)
+	}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for g := range latencies {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < cap(latencies[g]); i++ {
+				if w.Burst > 0 && i > 0 && i%w.Burst == 0 {
+					time.Sleep(w.BurstPause)
+				}
+				t := time.Now()
+				logf(msg, kvs...)
+				latencies[g] = append(latencies[g], time.Since(t))
+			}
+		}(g)
+	}
+	wg.Wait()
+	flushErr := l.Flush()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	all := make([]time.Duration, 0, w.Entries)
+	for _, ls := range latencies {
+		all = append(all, ls...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	r := Result{
+		Entries:  len(all),
+		Duration: elapsed,
+		FlushErr: flushErr,
+	}
+	if len(all) > 0 {
+		r.Throughput = float64(len(all)) / elapsed.Seconds()
+		r.P50 = all[len(all)/2]
+		r.P99 = all[len(all)*99/100]
+		r.Max = all[len(all)-1]
+		r.AllocsPerEntry = float64(after.Mallocs-before.Mallocs) / float64(len(all))
+		r.BytesPerEntry = float64(after.TotalAlloc-before.TotalAlloc) / float64(len(all))
+	}
+	return r
+}
+
+// 对应级别的带字段输出方法
+func levelFunc(l *logger.Logger, level logger.LogType) func(string, ...interface{}) {
+	switch level {
+	case logger.DEBUG:
+		return l.Debugw
+	case logger.NOTICE:
+		return l.Noticew
+	case logger.WARN:
+		return l.Warnw
+	case logger.ERROR:
+		return l.Errorw
+	case logger.CRITICAL:
+		return l.Criticalw
+	case logger.FATAL:
+		return l.Fatalw
+	}
+	return l.Infow
+}
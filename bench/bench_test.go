@@ -0,0 +1,27 @@
+package bench_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/whitewolfpipi/logger"
+	"github.com/whitewolfpipi/logger/bench"
+)
+
+func TestRun(t *testing.T) {
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.Start()
+	defer l.Close()
+
+	r := bench.Run(l, bench.Workload{Entries: 1000, Concurrency: 4, Fields: 2, Level: logger.INFO, Burst: 100})
+	if r.FlushErr != nil {
+		t.Fatal(r.FlushErr)
+	}
+	if r.Entries != 1000 || r.Throughput <= 0 || r.Max < r.P50 {
+		t.Fatalf("unexpected result: %s", r)
+	}
+	if got := l.Stats().Entries; got != 1000 {
+		t.Fatalf("logged %d entries, want 1000", got)
+	}
+}
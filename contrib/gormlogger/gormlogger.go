@@ -0,0 +1,130 @@
+// gorm v2 日志适配，ORM 的日志与 SQL 追踪经由 logger 的缓存/队列输出
+//
+//	db, err := gorm.Open(dialector, &gorm.Config{
+//		Logger: gormlogger.New(l, gormlogger.Config{SlowThreshold: 200 * time.Millisecond}),
+//	})
+package gormlogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/whitewolfpipi/logger"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// 适配配置
+type Config struct {
+	LogLevel                  gormlogger.LogLevel // gorm 日志级别，0 使用 Warn
+	SlowThreshold             time.Duration       // 慢查询阈值，超过时以 WARN 输出，0 表示不检查
+	IgnoreRecordNotFoundError bool                // 不将 ErrRecordNotFound 作为错误输出
+}
+
+// 实现 gorm.io/gorm/logger.Interface
+type Logger struct {
+	l   *logger.Logger
+	cfg Config
+}
+
+// 声明接口实现者
+var (
+	_ gormlogger.Interface = &Logger{}
+)
+
+/*
+ * 创建 gorm v2 日志适配
+ *
+ * SQL 追踪的 SQL、影响行数、耗时(毫秒)与调用位置作为字段输出：
+ * 出错时为 ERROR，慢查询为 WARN，其余在 LogLevel 为 Info 时以 INFO 输出。
+ * 调用位置为 gorm 之外的第一个栈帧，开启 SetReportCaller 时 caller 同样指向业务代码；
+ * 传入 gorm 的 context 经 SetContextExtractor 附加 trace_id、request_id 等字段。
+ */
+func New(l *logger.Logger, cfg Config) *Logger {
+	if cfg.LogLevel == 0 {
+		cfg.LogLevel = gormlogger.Warn
+	}
+	return &Logger{l: l, cfg: cfg}
+}
+
+func (g *Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	ng := *g
+	ng.cfg.LogLevel = level
+	return &ng
+}
+
+func (g *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if g.cfg.LogLevel >= gormlogger.Info {
+		frame := utils.CallerFrame()
+		g.l.LogAt(ctx, frame.PC, logger.INFO, sprintf(msg, data), "source", fileWithLine(frame))
+	}
+}
+
+func (g *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if g.cfg.LogLevel >= gormlogger.Warn {
+		frame := utils.CallerFrame()
+		g.l.LogAt(ctx, frame.PC, logger.WARN, sprintf(msg, data), "source", fileWithLine(frame))
+	}
+}
+
+func (g *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if g.cfg.LogLevel >= gormlogger.Error {
+		frame := utils.CallerFrame()
+		g.l.LogAt(ctx, frame.PC, logger.ERROR, sprintf(msg, data), "source", fileWithLine(frame))
+	}
+}
+
+func (g *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.cfg.LogLevel <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && g.cfg.LogLevel >= gormlogger.Error &&
+		(!g.cfg.IgnoreRecordNotFoundError || !errors.Is(err, gorm.ErrRecordNotFound)):
+		frame := utils.CallerFrame()
+		g.l.LogAt(ctx, frame.PC, logger.ERROR, "sql error", g.fields(frame, fc, elapsed, "error", err)...)
+	case g.cfg.SlowThreshold > 0 && elapsed > g.cfg.SlowThreshold && g.cfg.LogLevel >= gormlogger.Warn:
+		frame := utils.CallerFrame()
+		g.l.LogAt(ctx, frame.PC, logger.WARN, "slow sql", g.fields(frame, fc, elapsed, "threshold", g.cfg.SlowThreshold.String())...)
+	case g.cfg.LogLevel >= gormlogger.Info:
+		frame := utils.CallerFrame()
+		g.l.LogAt(ctx, frame.PC, logger.INFO, "sql", g.fields(frame, fc, elapsed)...)
+	}
+}
+
+// SQL 追踪的字段，影响行数未知(-1)时不输出
+func (g *Logger) fields(frame runtime.Frame, fc func() (string, int64), elapsed time.Duration, extra ...interface{}) []interface{} {
+	sql, rows := fc()
+	kvs := make([]interface{}, 0, 8+len(extra))
+	kvs = append(kvs, extra...)
+	kvs = append(kvs, "sql", sql)
+	if rows >= 0 {
+		kvs = append(kvs, "rows", rows)
+	}
+	kvs = append(kvs,
+		"elapsed_ms", float64(elapsed.Microseconds())/1000,
+		"source", fileWithLine(frame))
+	return kvs
+}
+
+// 调用位置的 "文件:行号"，同 utils.FileWithLineNum
+func fileWithLine(frame runtime.Frame) string {
+	if frame.PC == 0 {
+		return ""
+	}
+	return frame.File + ":" + strconv.Itoa(frame.Line)
+}
+
+func sprintf(msg string, data []interface{}) string {
+	if len(data) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, data...)
+}
@@ -0,0 +1,80 @@
+package gormlogger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whitewolfpipi/logger"
+	"github.com/whitewolfpipi/logger/contrib/gormlogger"
+	gl "gorm.io/gorm/logger"
+)
+
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	g := gormlogger.New(l, gormlogger.Config{SlowThreshold: time.Millisecond})
+
+	fc := func() (string, int64) { return "SELECT 1", 1 }
+	ctx := context.Background()
+	g.Trace(ctx, time.Now(), fc, nil)                         // 低于 Warn，不输出
+	g.Trace(ctx, time.Now().Add(-time.Second), fc, nil)       // 慢查询
+	g.Trace(ctx, time.Now(), fc, errors.New("duplicate key")) // 错误
+	g.LogMode(gl.Info).Trace(ctx, time.Now(), fc, nil)        // Info 级别输出全部 SQL
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{"slow sql", "duplicate key", "| sql |"} {
+		if !strings.Contains(lines[i], want) || !strings.Contains(lines[i], "sql=SELECT 1") || !strings.Contains(lines[i], "rows=1") {
+			t.Fatalf("line %d: missing %q in %s", i, want, lines[i])
+		}
+	}
+}
+
+type traceKey struct{}
+
+// 调用位置为业务代码而不是适配器，context 中的字段随日志输出
+func TestCallerAndContext(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.SetReportCaller(true)
+	l.SetContextExtractor(func(ctx context.Context) []logger.Field {
+		return []logger.Field{{Key: "trace_id", Value: ctx.Value(traceKey{})}}
+	})
+	g := gormlogger.New(l, gormlogger.Config{LogLevel: gl.Info})
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "t-1")
+	g.Info(ctx, "hello %s", "gorm")
+	g.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(fmt.Sprint(m["caller"]), "gormlogger_test.go") || m["trace_id"] != "t-1" {
+			t.Fatalf("unexpected entry %s", line)
+		}
+	}
+}
@@ -3,16 +3,67 @@ package logger
 // 定义格式函数
 type FormatFunc func(LogType, interface{}) (string, []interface{}, bool)
 
-// 定义日志接口
+/*
+ * 定义日志接口
+ *
+ * 只包含分级输出方法，库只依赖此接口即可，测试用的假日志只需实现这几个方法。
+ * 其他能力拆分为可选接口，需要时通过类型断言探测：
+ *
+ *   func Shutdown(l logger.ILogger) {
+ *       if f, ok := l.(logger.Flusher); ok {
+ *           f.Flush()
+ *       }
+ *   }
+ */
 type ILogger interface {
-	SetLogLevel(LogType)        // 设置日志级别
-	GetLogLevel() LogType       // 获取日志级别
+	Debug(...interface{})  // 打印debug日志
+	Info(...interface{})   // 打印info日志
+	Notice(...interface{}) // 打印notice日志
+	Warn(...interface{})   // 打印warn日志
+	Error(...interface{})  // 打印error日志
+	Fatal(...interface{})  // 打印fatal日志
+}
+
+// 可调整级别
+type Leveler interface {
+	SetLogLevel(LogType)  // 设置日志级别
+	GetLogLevel() LogType // 获取日志级别
+}
+
+// 可设置格式化函数
+type Formatter interface {
 	SetLoggerFormat(FormatFunc) // 设置日志格式
-	Debug(interface{})          // 打印debug日志
-	Info(interface{})           // 打印info日志
-	Notice(interface{})         // 打印notice日志
-	Warn(interface{})           // 打印warn日志
-	Error(interface{})          // 打印error日志
-	Fatal(interface{})          // 打印fatal日志
-	Print(interface{})          // 兼容gorm日志
+}
+
+// 兼容gorm v1日志
+type Printer interface {
+	Print(...interface{})
+}
+
+// 可同步写出缓存中的日志
+type Flusher interface {
+	Flush() error
+}
+
+// 可立即切换新文件
+type Rotator interface {
+	Rotate() error
+}
+
+// 带字段的输出，kvs 为交替的键值对
+type Structured interface {
+	Debugw(msg string, kvs ...interface{})
+	Infow(msg string, kvs ...interface{})
+	Noticew(msg string, kvs ...interface{})
+	Warnw(msg string, kvs ...interface{})
+	Errorw(msg string, kvs ...interface{})
+	Fatalw(msg string, kvs ...interface{})
+}
+
+// 原 ILogger 的全部方法，便于从旧接口迁移
+type StdLogger interface {
+	ILogger
+	Leveler
+	Formatter
+	Printer
 }
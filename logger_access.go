@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"strconv"
+	"time"
+)
+
+// 耗时着色的阈值
+type LatencyThresholds struct {
+	Warn time.Duration // 达到该耗时显示为黄色，0 表示不着色
+	Slow time.Duration // 达到该耗时显示为红色，0 表示不着色
+}
+
+// 默认阈值：200ms 黄色，1s 红色。需在输出日志前修改
+var DefaultLatencyThresholds = LatencyThresholds{Warn: 200 * time.Millisecond, Slow: time.Second}
+
+/*
+ * 按类别带颜色后缀的状态码数据列：2xx 绿色，4xx 黄色，5xx 红色，其余不着色
+ *
+ *   l.Info([]string{logger.StatusTag(code), "ok", logger.LatencyTag(d), "GET", "/hello"})
+ */
+func StatusTag(status int) string {
+	s := strconv.Itoa(status)
+	switch {
+	case status >= 500 && status < 600:
+		return s + "-r"
+	case status >= 400 && status < 500:
+		return s + "-y"
+	case status >= 200 && status < 300:
+		return s + "-g"
+	}
+	return s
+}
+
+// 按 DefaultLatencyThresholds 带颜色后缀的耗时数据列
+func LatencyTag(d time.Duration) string {
+	return DefaultLatencyThresholds.Tag(d)
+}
+
+// 按阈值带颜色后缀的耗时数据列，未达到阈值时不着色
+func (t LatencyThresholds) Tag(d time.Duration) string {
+	switch {
+	case t.Slow > 0 && d >= t.Slow:
+		return d.String() + "-r"
+	case t.Warn > 0 && d >= t.Warn:
+		return d.String() + "-y"
+	}
+	return d.String()
+}
+
+/*
+ * HTTP 访问日志
+ *
+ *   l.Access(logger.AccessEntry{
+ *       Status: 200, Method: r.Method, Path: r.URL.Path,
+ *       Latency: time.Since(start), Size: n, ClientIP: ip,
+ *   })
+ *
+ * 文本输出与切片形式的彩色列一致：状态码 | 耗时 | 客户端IP | 方法 | 路径 | 字节数，
+ * 状态码按类别、耗时按 DefaultLatencyThresholds 自动着色，见 StatusTag 与 LatencyTag；
+ * JSON 输出 msg 为 "方法 路径"，其余以 status、method、path、latency_ms、size、client_ip 字段输出。
+ */
+type AccessEntry struct {
+	Status   int           // 响应状态码
+	Method   string        // 请求方法
+	Path     string        // 请求路径
+	Latency  time.Duration // 处理耗时
+	Size     int64         // 响应字节数
+	ClientIP string        // 客户端IP，为空时不输出
+}
+
+/*
+ * 输出一条访问日志，级别按状态码决定：5xx 为 ERROR，4xx 为 WARN，其余为 INFO
+ * fields 为附加字段，如请求ID
+ */
+func (l *Logger) Access(a AccessEntry, fields ...Field) LogResult {
+	return l.log(a.level(), &a, fields...)
+}
+
+// 按状态码决定的级别
+func (a *AccessEntry) level() LogType {
+	switch {
+	case a.Status >= 500:
+		return ERROR
+	case a.Status >= 400:
+		return WARN
+	}
+	return INFO
+}
+
+// 带颜色后缀的数据列，同切片形式的日志
+func (a *AccessEntry) columns() []string {
+	cols := make([]string, 0, 6)
+	cols = append(cols, StatusTag(a.Status), LatencyTag(a.Latency))
+	if a.ClientIP != "" {
+		cols = append(cols, a.ClientIP)
+	}
+	return append(cols, a.Method+"-b", a.Path, strconv.FormatInt(a.Size, 10)+"B")
+}
+
+// JSON 等结构化输出的消息
+func (a *AccessEntry) message() string {
+	return a.Method + " " + a.Path
+}
+
+// 结构化输出的字段
+func (a *AccessEntry) fields() []Field {
+	fields := []Field{
+		{Key: "status", Value: a.Status},
+		{Key: "method", Value: a.Method},
+		{Key: "path", Value: a.Path},
+		{Key: "latency_ms", Value: float64(a.Latency) / float64(time.Millisecond)},
+		{Key: "size", Value: a.Size},
+	}
+	if a.ClientIP != "" {
+		fields = append(fields, Field{Key: "client_ip", Value: a.ClientIP})
+	}
+	return fields
+}
@@ -0,0 +1,252 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 保留的最近内部错误条数
+const recentErrorsSize = 20
+
+// 状态查询等待 l.mu 的最长时间，超时后只输出无锁读取的状态
+const adminLockWait = 100 * time.Millisecond
+
+// 内部错误记录
+type errorRecord struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// 管理接口输出的状态
+type adminStatus struct {
+	Config struct {
+		Level        string `json:"level"`
+		Name         string `json:"name"`
+		Cache        bool   `json:"cache"`
+		CacheMillis  int64  `json:"cache_duration_ms"`
+		CacheCap     int    `json:"cache_cap"`
+		QueueSize    int    `json:"queue_size"`
+		Overflow     string `json:"overflow"`
+		Encoder      string `json:"encoder"`
+		Color        string `json:"color"`
+		FormatFunc   bool   `json:"format_func"`
+		EntryID      bool   `json:"entry_id"`
+		ReportCaller bool   `json:"report_caller"`
+		MaxLatencyMs int64  `json:"max_latency_ms"`
+		Watchdog     int64  `json:"flush_watchdog"`
+	} `json:"config"`
+	Started bool `json:"started"`
+	Closed  bool `json:"closed"`
+	Busy    bool `json:"busy"` // 日志调用阻塞(如主输出卡住)，config 中只有级别等无锁读取的设置
+	Queue   struct {
+		Cached int `json:"cached"` // 缓存中待写条数
+		Queued int `json:"queued"` // 队列中待写条数
+	} `json:"queue"`
+	Sampler *samplerStatus `json:"sampler"` // 未开启采样时为 null
+	Sinks   []sinkStatus   `json:"sinks"`
+	Stats   Stats          `json:"stats"`
+	Errors  []errorRecord  `json:"recent_errors"`
+}
+
+// 采样状态
+type samplerStatus struct {
+	Initial    int    `json:"initial"`
+	Thereafter int    `json:"thereafter"`
+	Window     string `json:"window"`
+	Keys       int    `json:"keys"` // 当前窗口内的日志种类数
+}
+
+// 附加输出端状态
+type sinkStatus struct {
+	Writer         string `json:"writer"`
+	MinLevel       string `json:"min_level"`
+	Healthy        bool   `json:"healthy"` // 无写出失败积压
+	Pending        int    `json:"pending"`
+	BacklogBatches int    `json:"backlog_batches"`
+	BacklogBytes   int    `json:"backlog_bytes"`
+	Dropped        int64  `json:"dropped"`
+	Expired        int64  `json:"expired"`
+}
+
+/*
+ * 管理接口，以 JSON 输出配置、队列深度、附加输出端状态、统计和最近的内部错误，并可触发刷新
+ *
+ *   http.Handle("/debug/logger/", http.StripPrefix("/debug/logger", l.AdminHandler()))
+ *
+ *   GET  /status  状态
+ *   GET  /level   当前级别，PUT 修改级别，见 AtomicLevel
+ *   POST /flush   同步写出缓存和队列
+ *   POST /rotate  切换新文件(仅 RotateFileLogger)
+ */
+func (l *Logger) AdminHandler() http.Handler {
+	if l.noop() {
+		return http.NotFoundHandler()
+	}
+
+	return &adminHandler{l: l}
+}
+
+// 文件日志的管理接口，额外支持 /rotate
+func (l *RotateFileLogger) AdminHandler() http.Handler {
+	return &adminHandler{l: &l.Logger, rotate: l.Rotate}
+}
+
+// 管理接口
+type adminHandler struct {
+	l      *Logger
+	rotate func() error // 切换文件，nil 表示不支持
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "", "/status":
+		if r.Method != http.MethodGet {
+			adminError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.l.adminStatus())
+	case "/level":
+		h.l.LevelHandler().ServeHTTP(w, r)
+	case "/flush":
+		h.action(w, r, h.l.Flush)
+	case "/rotate":
+		if h.rotate == nil {
+			adminError(w, http.StatusNotImplemented, "rotation not supported")
+			return
+		}
+		h.action(w, r, h.rotate)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// 执行一个 POST 操作
+func (h *adminHandler) action(w http.ResponseWriter, r *http.Request, fn func() error) {
+	if r.Method != http.MethodPost {
+		adminError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err := fn(); err != nil {
+		adminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ok":true}` + "\n"))
+}
+
+func adminError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// 生成状态快照，派生日志返回根日志的状态
+func (l *Logger) adminStatus() *adminStatus {
+	if l.root != nil {
+		return l.root.adminStatus()
+	}
+
+	st := &adminStatus{}
+	st.Config.Level = strings.TrimSpace(GetLogTypeString(l.logLevel.Level()))
+	st.Config.Color = ColorMode(atomic.LoadInt32((*int32)(&l.colorMode))).String()
+	st.Config.MaxLatencyMs = int64(time.Duration(atomic.LoadInt64(&l.latency.limit)) / time.Millisecond)
+	st.Config.Watchdog = atomic.LoadInt64(&l.stall.intervals)
+
+	// 主输出卡住时日志调用持有 l.mu 等待，此时只输出无锁读取的状态
+	if !l.tryLock(adminLockWait) {
+		st.Busy = true
+	} else {
+		l.configStatus(st)
+		l.mu.Unlock()
+	}
+
+	l.cache.mutex.Lock()
+	st.Queue.Cached = len(l.cache.data)
+	l.cache.mutex.Unlock()
+	l.viewMu.Lock()
+	queue, sinks := l.view.queue, l.view.sinks
+	l.viewMu.Unlock()
+	st.Queue.Queued = len(queue)
+
+	st.Sinks = make([]sinkStatus, 0, len(sinks))
+	for _, s := range sinks {
+		st.Sinks = append(st.Sinks, s.status())
+	}
+	st.Stats = l.Stats()
+	st.Errors = l.recentErrors()
+	return st
+}
+
+// 在 timeout 内尝试获取 l.mu，成功返回 true
+func (l *Logger) tryLock(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !l.mu.TryLock() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// 填写由 l.mu 保护的配置与状态。调用方需持有 l.mu
+func (l *Logger) configStatus(st *adminStatus) {
+	st.Config.Name = l.name
+	st.Config.Cache = l.cache.use
+	st.Config.CacheMillis = int64(l.cache.duration)
+	st.Config.CacheCap = l.cache.cacheCap
+	st.Config.QueueSize = l.queueSize
+	st.Config.Overflow = l.overflow.String()
+	st.Config.Encoder = fmt.Sprintf("%T", l.encoder)
+	st.Config.FormatFunc = l.logFormatFunc != nil
+	st.Config.EntryID = l.entryID
+	st.Config.ReportCaller = l.reportCaller
+	st.Started = l.started
+	st.Closed = l.closed
+	if s := l.sampler; s != nil {
+		st.Sampler = &samplerStatus{
+			Initial:    s.cfg.Initial,
+			Thereafter: s.cfg.Thereafter,
+			Window:     s.cfg.Window.String(),
+			Keys:       len(s.state),
+		}
+	}
+}
+
+// 输出端状态快照
+func (s *Sink) status() sinkStatus {
+	ss := sinkStatus{
+		Writer:   fmt.Sprintf("%T", s.Writer),
+		MinLevel: strings.TrimSpace(GetLogTypeString(s.MinLevel)),
+		Dropped:  s.Dropped(),
+		Expired:  s.Expired(),
+	}
+	s.mu.Lock()
+	ss.Pending = len(s.pending)
+	s.mu.Unlock()
+	// 积压由写出时持有的 writeMu 保护，输出端卡住时仍需返回状态，读取原子计数
+	ss.BacklogBatches = int(atomic.LoadInt64(&s.backlogBatches))
+	ss.BacklogBytes = int(atomic.LoadInt64(&s.backlogBytes))
+	ss.Healthy = ss.BacklogBatches == 0
+	return ss
+}
+
+// 记录一条内部错误，保留最近 recentErrorsSize 条。调用方需持有 l.errMu
+func (l *Logger) recordError(err error) {
+	l.lastErrors = append(l.lastErrors, errorRecord{Time: time.Now(), Error: err.Error()})
+	if len(l.lastErrors) > recentErrorsSize {
+		l.lastErrors = append(l.lastErrors[:0], l.lastErrors[len(l.lastErrors)-recentErrorsSize:]...)
+	}
+}
+
+// 最近的内部错误，旧的在前
+func (l *Logger) recentErrors() []errorRecord {
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+	return append([]errorRecord{}, l.lastErrors...)
+}
@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// 输出端默认的积压内存上限
+const defaultSinkBacklogBytes = 32 << 20
+
+// 补发日志的标记字段，见 markReplayed
+const (
+	ReplayedKey   = "replayed"    // 值为 true
+	ReplayedAtKey = "replayed_at" // 补发时间，RFC3339Nano
+)
+
+// 写出失败后积压的一批日志
+type backlogBatch struct {
+	data       []byte // 日志内容，compressed 时为 gzip 数据
+	entries    int    // 条数
+	compressed bool   // 是否已压缩
+	replay     bool   // 因输出端故障推迟写出，写出时标记为补发
+	marked     bool   // data 已带补发标记
+}
+
+// 写出积压的批次和本次的日志，失败时将未写出的部分留在积压中等待下次重试
+// 调用方需持有 s.writeMu
+func (s *Sink) writeWithBacklog(msg []byte, entries int) error {
+	if s.disabled() {
+		atomic.AddInt64(&s.dropped, int64(entries))
+		return nil
+	}
+	if len(msg) > 0 {
+		// 积压中仍有未写出的批次时，本次的日志同样因故障推迟
+		s.appendBacklog(backlogBatch{data: append([]byte(nil), msg...), entries: entries, replay: len(s.backlog) > 0})
+	}
+
+	for len(s.backlog) > 0 {
+		batch := s.backlog[0]
+		data := batch.data
+		if batch.compressed {
+			var err error
+			if data, err = gunzipBytes(data); err != nil {
+				// 数据损坏，丢弃该批次
+				s.dropBatch(0)
+				return err
+			}
+		}
+		if batch.replay && !batch.marked && s.Separator <= SeparatorCRLF {
+			data = markReplayed(data, s.clock.Now())
+		}
+		if err := s.Chaos.inject(&s.chaos); err != nil {
+			s.deferBacklog()
+			return err
+		}
+		if n, err := s.Writer.Write(data); err != nil {
+			if n > 0 && n < len(data) {
+				// 只保留未写出的部分，重试时不重复写出
+				s.trimBatch(data[n:])
+			}
+			s.deferBacklog()
+			if outputClosed(s.Writer, err) {
+				s.disable()
+				return fmt.Errorf("%w: %v, disabled", ErrOutputClosed, err)
+			}
+			if isPermanent(err) {
+				// 重试无效，丢弃该批次
+				s.dropBatch(0)
+			}
+			return err
+		}
+		s.dropBatch(-1)
+	}
+	return nil
+}
+
+// 追加积压批次，超过压缩阈值时压缩较旧的批次，超过内存上限时丢弃新批次
+func (s *Sink) appendBacklog(batch backlogBatch) {
+	limit := s.BacklogBytes
+	if limit <= 0 {
+		limit = defaultSinkBacklogBytes
+	}
+
+	s.backlog = append(s.backlog, batch)
+	s.backlogSize += len(batch.data)
+
+	// 压缩除最新批次外未压缩的批次
+	if s.CompressThreshold > 0 && s.backlogSize > s.CompressThreshold {
+		for i := 0; i < len(s.backlog)-1; i++ {
+			b := &s.backlog[i]
+			if b.compressed {
+				continue
+			}
+			z, err := gzipBytes(b.data)
+			if err != nil || len(z) >= len(b.data) {
+				continue
+			}
+			s.backlogSize += len(z) - len(b.data)
+			b.data = z
+			b.compressed = true
+		}
+	}
+
+	if s.backlogSize > limit && len(s.backlog) > 1 {
+		s.dropBatch(len(s.backlog) - 1)
+	}
+	s.storeBacklog()
+}
+
+// 移除积压批次，index 为 -1 表示已成功写出的首个批次(不计入丢弃)
+func (s *Sink) dropBatch(index int) {
+	written := index < 0
+	if written {
+		index = 0
+	}
+	batch := s.backlog[index]
+	s.backlog = append(s.backlog[:index], s.backlog[index+1:]...)
+	s.backlogSize -= len(batch.data)
+	s.storeBacklog()
+	if !written {
+		atomic.AddInt64(&s.dropped, int64(batch.entries))
+	}
+}
+
+// 写出失败，积压中的批次都推迟到恢复后补发
+func (s *Sink) deferBacklog() {
+	for i := range s.backlog {
+		s.backlog[i].replay = true
+	}
+}
+
+// 首个批次部分写出后替换为未写出的内容，条数按剩余的完整行计
+func (s *Sink) trimBatch(rest []byte) {
+	batch := &s.backlog[0]
+	s.backlogSize += len(rest) - len(batch.data)
+	batch.data = append([]byte(nil), rest...)
+	batch.compressed = false
+	batch.marked = batch.replay
+	if n := bytes.Count(rest, []byte{'\n'}); n < batch.entries {
+		batch.entries = n
+	}
+	if batch.entries == 0 {
+		batch.entries = 1
+	}
+	s.storeBacklog()
+}
+
+// 更新可无锁读取的积压批次数与字节数
+func (s *Sink) storeBacklog() {
+	atomic.StoreInt64(&s.backlogBatches, int64(len(s.backlog)))
+	atomic.StoreInt64(&s.backlogBytes, int64(s.backlogSize))
+}
+
+/*
+ * 为补发的日志追加标记，日志自身的时间保持不变，下游可据此区分迟到的数据
+ * JSON 行在结尾的 } 前追加 "replayed":true,"replayed_at":"..."，文本行追加 replayed=true | replayed_at=... 两列，
+ * 无法识别的行不变
+ */
+func markReplayed(data []byte, at time.Time) []byte {
+	ts := at.Format(time.RFC3339Nano)
+	out := make([]byte, 0, len(data)+len(data)/4)
+	for len(data) > 0 {
+		n := bytes.IndexByte(data, '\n') + 1
+		if n == 0 {
+			n = len(data)
+		}
+		line := data[:n]
+		data = data[n:]
+		body := bytes.TrimRight(line, "\r\n")
+		switch {
+		case bytes.HasPrefix(body, []byte("{")) && bytes.HasSuffix(body, []byte("}")):
+			out = append(out, body[:len(body)-1]...)
+			out = append(out, `,"`+ReplayedKey+`":true,"`+ReplayedAtKey+`":"`+ts+`"}`...)
+		case bytes.HasSuffix(body, []byte(" | ")):
+			out = append(out, body...)
+			out = append(out, ReplayedKey+"=true | "+ReplayedAtKey+"="+ts+" | "...)
+		default:
+			out = append(out, body...)
+		}
+		out = append(out, line[len(body):]...)
+	}
+	return out
+}
+
+// 是否为重试无效的错误：错误链中有 Permanent() 返回 true 的错误，见 sinkapi.Permanent
+func isPermanent(err error) bool {
+	var p interface{ Permanent() bool }
+	return errors.As(err, &p) && p.Permanent()
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxPooledBufferSize = 64 << 10 // 超过该容量的缓冲不放回池中，避免偶发的大日志长期占用内存
+	maxCacheBufferSize  = 4 << 20  // 缓存缓冲排空后保留的容量上限
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &Buffer{b: make([]byte, 0, 256)}
+	},
+}
+
+/*
+ * 编码缓冲
+ *
+ * 基于 []byte，通过 GetBuffer 从缓冲池获取，用完调用 Free 归还。日志编码后的缓冲直接进入缓存或队列，
+ * 写出后归还，整个输出通路不产生中间字符串。自定义编码器实现 BufferEncoder 即可直接编码到缓冲。
+ */
+type Buffer struct {
+	b []byte
+}
+
+// 可直接编码到缓冲的编码器，输出时优先使用 EncodeTo，避免每条日志分配内存
+type BufferEncoder interface {
+	Encoder
+	EncodeTo(buf *Buffer, e *Entry) error
+}
+
+// 从缓冲池获取一个空缓冲
+func GetBuffer() *Buffer {
+	buf := bufferPool.Get().(*Buffer)
+	buf.b = buf.b[:0]
+	return buf
+}
+
+// 归还缓冲，之后不能再使用
+func (buf *Buffer) Free() {
+	if cap(buf.b) > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+func (buf *Buffer) Bytes() []byte {
+	return buf.b
+}
+
+func (buf *Buffer) String() string {
+	return string(buf.b)
+}
+
+func (buf *Buffer) Len() int {
+	return len(buf.b)
+}
+
+func (buf *Buffer) Reset() {
+	buf.b = buf.b[:0]
+}
+
+func (buf *Buffer) Write(p []byte) (int, error) {
+	buf.b = append(buf.b, p...)
+	return len(p), nil
+}
+
+func (buf *Buffer) WriteString(s string) (int, error) {
+	buf.b = append(buf.b, s...)
+	return len(s), nil
+}
+
+func (buf *Buffer) WriteByte(c byte) error {
+	buf.b = append(buf.b, c)
+	return nil
+}
+
+func (buf *Buffer) AppendInt(i int64) {
+	buf.b = strconv.AppendInt(buf.b, i, 10)
+}
+
+// 按 layout 追加时间，同 time.Time.AppendFormat
+func (buf *Buffer) AppendTime(t time.Time, layout string) {
+	buf.b = t.AppendFormat(buf.b, layout)
+}
+
+// 追加字段值，常见类型不经过 fmt，输出与 fmt.Sprint 一致
+func (buf *Buffer) AppendValue(v interface{}) {
+	switch v := v.(type) {
+	case string:
+		buf.b = append(buf.b, v...)
+	case int:
+		buf.b = strconv.AppendInt(buf.b, int64(v), 10)
+	case int64:
+		buf.b = strconv.AppendInt(buf.b, v, 10)
+	case int32:
+		buf.b = strconv.AppendInt(buf.b, int64(v), 10)
+	case uint:
+		buf.b = strconv.AppendUint(buf.b, uint64(v), 10)
+	case uint64:
+		buf.b = strconv.AppendUint(buf.b, v, 10)
+	case uint32:
+		buf.b = strconv.AppendUint(buf.b, uint64(v), 10)
+	case bool:
+		buf.b = strconv.AppendBool(buf.b, v)
+	case time.Duration:
+		buf.b = append(buf.b, v.String()...)
+	default:
+		fmt.Fprint(buf, v)
+	}
+}
+
+var entryPool = sync.Pool{
+	New: func() interface{} {
+		return &Entry{}
+	},
+}
+
+// 从池中获取日志条目
+func getEntry() *Entry {
+	return entryPool.Get().(*Entry)
+}
+
+// 归还日志条目，清空引用的数据
+func putEntry(e *Entry) {
+	*e = Entry{}
+	entryPool.Put(e)
+}
+
+// 编码到缓冲，编码器未实现 BufferEncoder 时复制 Encode 的结果
+func encodeTo(enc Encoder, buf *Buffer, e *Entry) error {
+	if be, ok := enc.(BufferEncoder); ok {
+		return be.EncodeTo(buf, e)
+	}
+	b, err := enc.Encode(e)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// 追加 JSON 字符串，转义规则与 encoding/json 一致(含 HTML 字符转义)
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// 调用链字段的键
+const CallerChainKey = "callers"
+
+// 调用链为定位自身时多取的栈帧数
+const callerChainSlack = 16
+
+// 调用位置
+type Caller struct {
+	File     string // 文件完整路径
+	Line     int    // 行号
+	Function string // 函数全名，如 main.(*Server).Serve
+}
+
+// 返回 "目录/文件:行号" 形式的短位置
+func (c *Caller) String() string {
+	dir, file := filepath.Split(c.File)
+	return filepath.Join(filepath.Base(dir), file) + ":" + strconv.Itoa(c.Line)
+}
+
+// 设置是否记录调用位置(文件、行号、函数)，记录后交给编码器输出
+// 需要获取调用栈，有一定开销
+func (l *Logger) SetReportCaller(enable bool) {
+	if l.noop() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reportCaller = enable
+}
+
+// 设置额外跳过的调用栈层数，用于在自定义的封装函数中调用日志方法时定位到封装函数的调用方
+func (l *Logger) SetCallerSkip(skip int) {
+	if l.noop() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.callerSkip = skip
+}
+
+/*
+ * 设置某一级别记录的调用链层数，depth 为 0 表示不记录
+ *
+ *   l.SetCallerChain(logger.ERROR, 3)
+ *   l.Error("query failed") // callers=db.go:42<repo.go:17<handler.go:88
+ *
+ * 调用链为调用方起向上 depth 层的 "文件名:行号"，以 "<" 连接，作为 callers 字段输出，
+ * 比完整的调用栈轻量，又能看出是从哪条路径调用的。与 SetReportCaller 相互独立，同样有获取调用栈的开销
+ */
+func (l *Logger) SetCallerChain(level LogType, depth int) {
+	if l.noop() {
+		return
+	}
+
+	if level < DEBUG || level > FATAL {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.callerChains[level] = depth
+}
+
+// 获取调用位置的程序计数器，skip 为 0 表示 callerPC 的调用方
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+
+// 由程序计数器解析调用位置
+func newCaller(pc uintptr) *Caller {
+	if pc == 0 {
+		return nil
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return &Caller{File: frame.File, Line: frame.Line, Function: frame.Function}
+}
+
+// 从 pc 所在的栈帧起向上 depth 层的调用链，skip 同 callerPC；pc 不在当前调用栈中时只含 pc
+func callerChain(skip int, pc uintptr, depth int) string {
+	pcs := make([]uintptr, depth+callerChainSlack)
+	n := runtime.Callers(skip+2, pcs)
+	pcs = pcs[:n]
+	if pc != 0 {
+		i := 0
+		for i < len(pcs) && pcs[i] != pc {
+			i++
+		}
+		if i == len(pcs) {
+			pcs = []uintptr{pc}
+		} else {
+			pcs = pcs[i:]
+		}
+	}
+	if len(pcs) > depth {
+		pcs = pcs[:depth]
+	}
+
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.File != "" {
+			if b.Len() > 0 {
+				b.WriteByte('<')
+			}
+			b.WriteString(filepath.Base(frame.File))
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 故障注入模拟的写出失败
+var ErrChaos = errors.New("logger: chaos: injected write failure")
+
+/*
+ * 输出端的故障注入，用于在演练中验证丢弃策略、积压重试等是否按预期工作
+ *
+ *   l.AddSink(&logger.Sink{
+ *       Writer: conn,
+ *       Chaos:  &logger.Chaos{Seed: 42, FailRate: 0.2, DelayRate: 0.1, MaxDelay: time.Second, ReorderRate: 0.05},
+ *   })
+ *
+ * 每次写出前按比例随机等待至多 MaxDelay、或返回 ErrChaos 模拟输出端故障(日志留在积压中重试)；
+ * 每批待写日志按 ReorderRate 随机交换顺序。相同 Seed 产生相同的随机序列，便于复现。
+ * 只用于测试环境，生产配置中不应开启。
+ */
+type Chaos struct {
+	Seed        int64         // 随机种子，0 使用当前时间
+	DelayRate   float64       // 写出前等待的比例
+	MaxDelay    time.Duration // 单次等待的上限
+	FailRate    float64       // 写出失败的比例
+	ReorderRate float64       // 每条日志与之后的日志交换顺序的比例
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// 注入的故障次数
+type ChaosStats struct {
+	Delays   int64 // 等待次数
+	Failures int64 // 失败次数
+	Reorders int64 // 交换顺序的次数
+}
+
+// 按比例抽样，调用方需持有 c.mu
+func (c *Chaos) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if c.rnd == nil {
+		seed := c.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		c.rnd = rand.New(rand.NewSource(seed))
+	}
+	return rate >= 1 || c.rnd.Float64() < rate
+}
+
+// 写出前按比例等待或返回 ErrChaos，c 为 nil 时不注入
+func (c *Chaos) inject(stats *ChaosStats) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	var delay time.Duration
+	if c.MaxDelay > 0 && c.hit(c.DelayRate) {
+		delay = time.Duration(c.rnd.Int63n(int64(c.MaxDelay)) + 1)
+		stats.Delays++
+	}
+	fail := c.hit(c.FailRate)
+	if fail {
+		stats.Failures++
+	}
+	c.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if fail {
+		return ErrChaos
+	}
+	return nil
+}
+
+// 按比例随机交换待写日志的顺序，c 为 nil 时不变
+func (c *Chaos) reorder(items []queued, stats *ChaosStats) {
+	if c == nil || len(items) < 2 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < len(items)-1; i++ {
+		if c.hit(c.ReorderRate) {
+			j := i + 1 + c.rnd.Intn(len(items)-i-1)
+			items[i], items[j] = items[j], items[i]
+			stats.Reorders++
+		}
+	}
+}
+
+// 输出端注入的故障次数
+func (s *Sink) ChaosStats() ChaosStats {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.chaos
+}
+
+// 故障注入配置
+type ChaosConfig struct {
+	Seed        int64   `json:"seed"`         // 随机种子，0 使用当前时间
+	DelayRate   float64 `json:"delay_rate"`   // 写出前等待的比例
+	MaxDelay    string  `json:"max_delay"`    // 单次等待的上限，如 "500ms"
+	FailRate    float64 `json:"fail_rate"`    // 写出失败的比例
+	ReorderRate float64 `json:"reorder_rate"` // 交换顺序的比例
+}
+
+// 将配置转为故障注入
+func (cc ChaosConfig) chaos() (*Chaos, error) {
+	for _, r := range []struct {
+		name string
+		rate float64
+	}{{"delay_rate", cc.DelayRate}, {"fail_rate", cc.FailRate}, {"reorder_rate", cc.ReorderRate}} {
+		if r.rate < 0 || r.rate > 1 {
+			return nil, fmt.Errorf("%s %v is not between 0 and 1", r.name, r.rate)
+		}
+	}
+	maxDelay, ok := parseConfigDuration(cc.MaxDelay)
+	if !ok || maxDelay < 0 {
+		return nil, fmt.Errorf("invalid max_delay %q", cc.MaxDelay)
+	}
+	return &Chaos{
+		Seed:        cc.Seed,
+		DelayRate:   cc.DelayRate,
+		MaxDelay:    maxDelay,
+		FailRate:    cc.FailRate,
+		ReorderRate: cc.ReorderRate,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package logger
+
+import "time"
+
+/*
+ * 时间来源，日志时间戳、缓存刷新定时器、日志过期判断与积压重放的标记时间都由它提供
+ *
+ * 离散事件仿真和回放工具可实现虚拟时间，推进时钟即可驱动日志的时间戳与定时刷新：
+ *
+ *   l.SetClock(sim.Clock())
+ *   l.Start()
+ */
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// 定时器，同 time.Ticker
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// 系统时间
+type systemClock struct{}
+
+// 系统定时器
+type systemTicker struct {
+	*time.Ticker
+}
+
+// 声明接口实现者
+var (
+	_ Clock  = systemClock{}
+	_ Ticker = systemTicker{}
+)
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (t systemTicker) Chan() <-chan time.Time {
+	return t.C
+}
+
+// 设置时间来源，为 nil 时恢复系统时间。定时器在 Start 时创建，需在 Start 之前设置
+// 派生日志设置根日志的时间来源
+func (l *Logger) SetClock(c Clock) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetClock(c)
+		return
+	}
+
+	if c == nil {
+		c = systemClock{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = c
+}
+
+// 当前时间，调用方需持有根日志的 l.mu
+func (l *Logger) now() time.Time {
+	return l.clock.Now()
+}
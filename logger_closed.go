@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+)
+
+// 主输出或输出端已不可用而停用，可通过 errors.Is 判断
+var ErrOutputClosed = errors.New("logger: output closed")
+
+// 写出错误是否表示文件输出已不可用：进程转为守护进程后标准输出被关闭(EBADF、os.ErrClosed)，
+// 或管道的读端已退出(EPIPE)。重试无效，应停用该输出
+func outputClosed(w io.Writer, err error) bool {
+	if _, ok := w.(*os.File); !ok {
+		return false
+	}
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.EBADF) || errors.Is(err, os.ErrClosed)
+}
+
+// 停用已不可用的主输出，改写备用输出，备用输出同样不可用时丢弃。调用方需持有 l.outMu
+func (l *Logger) disableOut() {
+	if l.fallback != nil && l.fallback != l.out {
+		l.setOut(l.fallback)
+		return
+	}
+	l.setOut(io.Discard)
+}
+
+// 停用已不可用的输出端，丢弃积压并计数。调用方需持有 s.writeMu
+func (s *Sink) disable() {
+	atomic.StoreInt32(&s.closed, 1)
+	for len(s.backlog) > 0 {
+		s.dropBatch(0)
+	}
+}
+
+// 输出端是否已停用
+func (s *Sink) disabled() bool {
+	return atomic.LoadInt32(&s.closed) == 1
+}
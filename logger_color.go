@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// 终端颜色模式
+type ColorMode int32
+
+const (
+	ColorAuto   = ColorMode(0) // 输出为终端时带颜色(默认)，重定向到文件或管道时去除颜色
+	ColorAlways = ColorMode(1) // 总是保留颜色
+	ColorNever  = ColorMode(2) // 总是去除颜色
+)
+
+// 不带颜色的文本编码器，去除颜色时替代 TextEncoder{Color: true}
+var plainTextEncoder = &TextEncoder{}
+
+/*
+ * 设置终端颜色模式
+ *
+ *   l.SetColorMode(logger.ColorNever) // 如输出交给 journald 时
+ *
+ * ColorAuto 按主输出(及各附加输出端)是否为终端决定；去除颜色时文本编码器不输出颜色，
+ * 自定义格式化函数生成的颜色转义码被去除。Windows 控制台在使用颜色时自动开启 VT 转义序列支持。
+ */
+func (l *Logger) SetColorMode(mode ColorMode) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetColorMode(mode)
+		return
+	}
+
+	atomic.StoreInt32((*int32)(&l.colorMode), int32(mode))
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+	l.setOut(l.out)
+}
+
+// 设置主输出并检测是否为终端，调用方需持有 l.outMu
+func (l *Logger) setOut(w io.Writer) {
+	l.out = w
+	atomic.StoreInt32(&l.outTTY, int32(l.detectTTY(w)))
+}
+
+// 检测输出是否为可显示颜色的终端，返回 1 或 0
+func (l *Logger) detectTTY(w io.Writer) int {
+	if ColorMode(atomic.LoadInt32((*int32)(&l.colorMode))) == ColorNever || !isTerminal(w) {
+		return 0
+	}
+	// Windows 控制台需开启 VT 处理才能显示颜色
+	if !enableVirtualTerminal(w) {
+		return 0
+	}
+	return 1
+}
+
+// 主输出是否去除颜色
+func (l *Logger) colorOff() bool {
+	switch ColorMode(atomic.LoadInt32((*int32)(&l.colorMode))) {
+	case ColorAlways:
+		return false
+	case ColorNever:
+		return true
+	}
+	return atomic.LoadInt32(&l.outTTY) == 0
+}
+
+// 附加输出端是否去除颜色
+func (l *Logger) sinkColorOff(s *Sink) bool {
+	switch ColorMode(atomic.LoadInt32((*int32)(&l.colorMode))) {
+	case ColorAlways:
+		return false
+	case ColorNever:
+		return true
+	}
+	return !s.tty
+}
+
+// 去除颜色时使用的编码器
+func (l *Logger) colorEncoder(enc Encoder, off bool) Encoder {
+	if te, ok := enc.(*TextEncoder); ok && te.Color && off {
+		return plainTextEncoder
+	}
+	return enc
+}
+
+// 去除颜色转义码
+func stripColor(s string) string {
+	if strings.IndexByte(s, '\033') < 0 {
+		return s
+	}
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// 由名称解析颜色模式，空串为 ColorAuto
+func parseColorMode(s string) (ColorMode, bool) {
+	for _, m := range []ColorMode{ColorAuto, ColorAlways, ColorNever} {
+		if s == m.String() {
+			return m, true
+		}
+	}
+	return ColorAuto, s == ""
+}
+
+func (m ColorMode) String() string {
+	switch m {
+	case ColorAuto:
+		return "auto"
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	}
+	return "unknown"
+}
@@ -0,0 +1,268 @@
+package logger
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/*
+ * 声明式日志配置，可由 JSON 等配置文件解析得到
+ *
+ *   {
+ *     "level": "INFO",
+ *     "encoding": "json",
+ *     "output": "file:///var/log/app",
+ *     "rotate": {"interval": "24h", "max_size": 104857600, "max_backups": 7, "compress": true},
+ *     "sinks": [{"url": "stderr", "level": "ERROR", "encoding": "text"}]
+ *   }
+ *
+ * 部署前可用 ValidateConfig 检查配置。
+ */
+type Config struct {
+	Level         string       `json:"level"`          // 日志级别，如 "INFO"，为空时保持原级别(新建日志为 DEBUG)
+	Source        string       `json:"source"`         // 日志来源，设置后每条日志带 source 字段
+	Encoding      string       `json:"encoding"`       // 编码 text|json，为空使用 text
+	Color         string       `json:"color"`          // 颜色模式 auto|always|never，为空使用 auto
+	Mode          string       `json:"mode"`           // 写出模式 cache|queue，为空按输出决定
+	CacheDuration string       `json:"cache_duration"` // 缓存刷新周期，如 "100ms"
+	QueueSize     int          `json:"queue_size"`     // 队列大小
+	Overflow      string       `json:"overflow"`       // 队列满时的处理策略 block|drop_newest|drop_oldest
+	MemoryLimit   int64        `json:"memory_limit"`   // 待写日志占用内存的上限(字节)，0 表示不限制
+	Output        string       `json:"output"`         // 主输出 stdout|stderr|file://目录，为空使用 stdout
+	Separator     string       `json:"separator"`      // 主输出的记录分隔 lf|crlf|nul|length，为空使用 lf
+	Rotate        RotateConfig `json:"rotate"`         // 文件切换，仅主输出为文件时有效
+	Sinks         []SinkConfig `json:"sinks"`          // 附加输出端
+	Tenants       TenantConfig `json:"tenants"`        // 按租户的级别、采样与保留策略
+}
+
+// 文件切换配置
+type RotateConfig struct {
+	Interval   string `json:"interval"`    // 按时间切换的间隔，如 "1h"
+	Slice      string `json:"slice"`       // 时间切片长度，如 "30s"，文件名带序号
+	MaxSize    int64  `json:"max_size"`    // 单个文件大小上限(字节)
+	MaxAge     string `json:"max_age"`     // 旧文件保留时长，如 "168h"
+	MaxBackups int    `json:"max_backups"` // 旧文件保留个数
+	Compress   bool   `json:"compress"`    // 是否压缩旧文件
+	BufferSize int    `json:"buffer_size"` // 写入缓冲大小(字节)，0 表示不使用缓冲
+	Marker     bool   `json:"marker"`      // 是否在切换后的旧文件末尾写入标记行
+	Encoding   string `json:"encoding"`    // 文件编码 utf8|utf8-bom|utf16le，为空使用 utf8
+}
+
+// 租户策略配置，见 TenantPolicies
+type TenantConfig struct {
+	Key      string                        `json:"key"`      // 租户字段名，为空表示不按租户区分
+	Default  TenantPolicyConfig            `json:"default"`  // 未单独配置的租户的策略
+	Policies map[string]TenantPolicyConfig `json:"policies"` // 按租户ID的策略
+}
+
+// 单个租户的策略配置
+type TenantPolicyConfig struct {
+	Level      string  `json:"level"`       // 最低级别
+	SampleRate float64 `json:"sample_rate"` // 保留比例，0 或 1 表示全部保留
+	Retention  string  `json:"retention"`   // 保留期限，如 "720h"
+}
+
+// 附加输出端配置
+type SinkConfig struct {
+	URL         string   `json:"url"`          // stdout|stderr|file:///路径/文件|tcp://主机:端口|udp://主机:端口|syslog://[主机:端口]|syslog+tcp://主机:端口
+	Level       string   `json:"level"`        // 最低输出级别，为空使用 DEBUG
+	Encoding    string   `json:"encoding"`     // 编码 text|json，为空使用日志的编码
+	BufferSize  int      `json:"buffer_size"`  // 待写条数上限
+	Separator   string   `json:"separator"`    // 记录分隔 lf|crlf|nul|length，为空使用 lf
+	AllowFields []string `json:"allow_fields"` // 只输出这些字段
+	DenyFields  []string `json:"deny_fields"`  // 不输出这些字段
+
+	Chaos *ChaosConfig `json:"chaos"` // 故障注入，仅用于测试
+}
+
+// 支持的输出地址协议
+var outputSchemes = map[string]bool{"stdout": true, "stderr": true, "file": true,
+	"tcp": true, "udp": true, "syslog": true, "syslog+tcp": true}
+
+// 只能用于附加输出端的网络协议
+var networkSchemes = map[string]bool{"tcp": true, "udp": true, "syslog": true, "syslog+tcp": true}
+
+/*
+ * 检查配置而不创建任何日志、文件或连接，返回发现的全部问题，无问题时返回 nil
+ * 检查未知的级别、编码、输出协议，非法的时长，以及相互冲突的切换选项
+ */
+func ValidateConfig(cfg Config) []error {
+	var errs []error
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("logger: config %s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if cfg.Level != "" {
+		if _, err := ParseLogType(cfg.Level); err != nil {
+			fail("level", "unknown level %q", cfg.Level)
+		}
+	}
+	if !validEncoding(cfg.Encoding) {
+		fail("encoding", "unknown encoding %q", cfg.Encoding)
+	}
+	if _, ok := parseColorMode(cfg.Color); !ok {
+		fail("color", "unknown color mode %q", cfg.Color)
+	}
+	if cfg.Mode != "" && cfg.Mode != "cache" && cfg.Mode != "queue" {
+		fail("mode", "unknown mode %q", cfg.Mode)
+	}
+	if d, ok := parseConfigDuration(cfg.CacheDuration); !ok || d < 0 {
+		fail("cache_duration", "invalid duration %q", cfg.CacheDuration)
+	} else if cfg.Mode == "queue" && d > 0 {
+		fail("cache_duration", "set but mode is queue")
+	}
+	if cfg.QueueSize < 0 {
+		fail("queue_size", "must not be negative")
+	}
+	if cfg.MemoryLimit < 0 {
+		fail("memory_limit", "must not be negative")
+	}
+	if cfg.Source != "" && strings.TrimSpace(cfg.Source) == "" {
+		fail("source", "must not be blank")
+	}
+	if _, err := ParseOverflowPolicy(cfg.Overflow); err != nil {
+		fail("overflow", "unknown policy %q", cfg.Overflow)
+	} else if cfg.Overflow != "" && cfg.Mode == "cache" {
+		fail("overflow", "set but mode is cache")
+	}
+
+	scheme, path, err := parseOutputURL(cfg.Output)
+	if err != nil {
+		fail("output", "%v", err)
+	} else if scheme == "file" && path == "" {
+		fail("output", "file output needs a directory")
+	} else if networkSchemes[scheme] {
+		fail("output", "%s is only supported for sinks", scheme)
+	}
+	if _, err := ParseSeparator(cfg.Separator); err != nil {
+		fail("separator", "unknown separator %q", cfg.Separator)
+	}
+	errs = append(errs, validateRotate(cfg.Rotate, scheme == "file")...)
+	errs = append(errs, validateTenants(cfg.Tenants)...)
+
+	for i, s := range cfg.Sinks {
+		field := fmt.Sprintf("sinks[%d]", i)
+		if scheme, path, err := parseOutputURL(s.URL); err != nil || s.URL == "" {
+			if err == nil {
+				err = fmt.Errorf("url is required")
+			}
+			fail(field+".url", "%v", err)
+		} else if scheme == "file" && path == "" {
+			fail(field+".url", "file sink needs a path")
+		} else if networkSchemes[scheme] && scheme != "syslog" && path == "" {
+			fail(field+".url", "%s sink needs host:port", scheme)
+		} else if strings.HasPrefix(scheme, "syslog") && s.Encoding != "" {
+			fail(field+".encoding", "syslog sink does not support encoding")
+		}
+		if s.Level != "" {
+			if _, err := ParseLogType(s.Level); err != nil {
+				fail(field+".level", "unknown level %q", s.Level)
+			}
+		}
+		if !validEncoding(s.Encoding) {
+			fail(field+".encoding", "unknown encoding %q", s.Encoding)
+		}
+		if s.BufferSize < 0 {
+			fail(field+".buffer_size", "must not be negative")
+		}
+		if _, err := ParseSeparator(s.Separator); err != nil {
+			fail(field+".separator", "unknown separator %q", s.Separator)
+		}
+		for _, key := range s.AllowFields {
+			if containsString(s.DenyFields, key) {
+				fail(field+".allow_fields", "field %q is also denied", key)
+			}
+		}
+		if s.Chaos != nil {
+			if _, err := s.Chaos.chaos(); err != nil {
+				fail(field+".chaos", "%v", err)
+			}
+		}
+	}
+
+	return errs
+}
+
+// 检查文件切换配置
+func validateRotate(r RotateConfig, fileOutput bool) []error {
+	var errs []error
+	fail := func(field, format string, args ...interface{}) {
+		if field != "" {
+			field = "." + field
+		}
+		errs = append(errs, fmt.Errorf("logger: config rotate%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	interval, ok := parseConfigDuration(r.Interval)
+	if !ok || interval < 0 {
+		fail("interval", "invalid duration %q", r.Interval)
+	} else if interval > 0 && interval < time.Minute {
+		// 文件名精确到分钟，更短的间隔会写入同一文件
+		fail("interval", "%s is shorter than one minute", interval)
+	}
+	slice, ok := parseConfigDuration(r.Slice)
+	if !ok || slice < 0 {
+		fail("slice", "invalid duration %q", r.Slice)
+	} else if slice > 0 && interval > 0 {
+		fail("slice", "cannot be combined with interval")
+	}
+	if age, ok := parseConfigDuration(r.MaxAge); !ok || age < 0 {
+		fail("max_age", "invalid duration %q", r.MaxAge)
+	}
+	if r.MaxSize < 0 {
+		fail("max_size", "must not be negative")
+	}
+	if r.MaxBackups < 0 {
+		fail("max_backups", "must not be negative")
+	}
+	if r.BufferSize < 0 {
+		fail("buffer_size", "must not be negative")
+	}
+	if _, err := ParseFileEncoding(r.Encoding); err != nil {
+		fail("encoding", "unknown file encoding %q", r.Encoding)
+	}
+
+	configured := r != RotateConfig{}
+	if configured && !fileOutput {
+		fail("", "rotation options require a file output")
+	} else if interval == 0 && slice == 0 && r.MaxSize == 0 && (r.Compress || r.MaxBackups > 0 || r.MaxAge != "") {
+		fail("", "compress and retention options have no effect without interval, slice or max_size")
+	}
+	return errs
+}
+
+// 编码名称是否有效，空表示默认
+func validEncoding(enc string) bool {
+	return enc == "" || enc == "text" || enc == "json"
+}
+
+// 解析配置中的时长，空串为 0
+func parseConfigDuration(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+// 解析输出地址，返回协议和路径；stdout、stderr 可不带 "://"
+func parseOutputURL(s string) (scheme, path string, err error) {
+	if s == "" {
+		return "stdout", "", nil
+	}
+	if !strings.Contains(s, "://") {
+		scheme = s
+	} else {
+		u, err := url.Parse(s)
+		if err != nil {
+			return "", "", err
+		}
+		scheme, path = u.Scheme, u.Host+u.Path
+	}
+	if !outputSchemes[scheme] {
+		return "", "", fmt.Errorf("unknown scheme %q", scheme)
+	}
+	return scheme, path, nil
+}
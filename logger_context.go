@@ -0,0 +1,120 @@
+package logger
+
+import "context"
+
+// 从 context 提取日志字段，如请求ID、链路追踪的 trace_id/span_id
+type ContextExtractor func(ctx context.Context) []Field
+
+// context 中存放字段的键
+type contextFieldsKey struct{}
+
+/*
+ * 设置从 context 提取字段的函数，WithContext 和 InfoCtx 等方法输出时附加提取的字段
+ *
+ *   l.SetContextExtractor(func(ctx context.Context) []logger.Field {
+ *       sc := trace.SpanContextFromContext(ctx) // OpenTelemetry
+ *       if !sc.IsValid() {
+ *           return nil
+ *       }
+ *       return []logger.Field{{Key: "trace_id", Value: sc.TraceID().String()}, {Key: "span_id", Value: sc.SpanID().String()}}
+ *   })
+ *
+ * 派生日志使用根日志的提取函数。通过 ContextWithFields 存入的字段无需提取函数即可输出。
+ */
+func (l *Logger) SetContextExtractor(extractor ContextExtractor) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetContextExtractor(extractor)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ctxExtractor = extractor
+}
+
+/*
+ * 返回携带日志字段的 context，字段追加在 ctx 已有字段之后
+ *
+ *   ctx = logger.ContextWithFields(ctx, "request_id", id)
+ *   l.InfoCtx(ctx, "开始处理") // 输出 request_id
+ */
+func ContextWithFields(ctx context.Context, kvs ...interface{}) context.Context {
+	fields := kvsToFields(kvs)
+	if len(fields) == 0 {
+		return ctx
+	}
+	if old, ok := ctx.Value(contextFieldsKey{}).([]Field); ok {
+		fields = append(old[:len(old):len(old)], fields...)
+	}
+	return context.WithValue(ctx, contextFieldsKey{}, fields)
+}
+
+// 派生附加 ctx 中字段的日志，适合在一次请求中多次输出
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	return l.WithFields(l.contextFields(ctx)...)
+}
+
+// 提取 ctx 中的字段：ContextWithFields 存入的字段在前，提取函数的结果在后
+func (l *Logger) contextFields(ctx context.Context) []Field {
+	if ctx == nil || l.noop() {
+		return nil
+	}
+
+	root := l
+	if l.root != nil {
+		root = l.root
+	}
+	root.mu.Lock()
+	extractor := root.ctxExtractor
+	root.mu.Unlock()
+
+	fields, _ := ctx.Value(contextFieldsKey{}).([]Field)
+	if extractor == nil {
+		return fields
+	}
+	if extracted := extractor(ctx); len(extracted) > 0 {
+		fields = append(fields[:len(fields):len(fields)], extracted...)
+	}
+	return fields
+}
+
+// 级别被过滤时不提取字段
+func (l *Logger) levelContextFields(ctx context.Context, logType LogType) []Field {
+	if l.noop() || l.logLevel.Level() > logType {
+		return nil
+	}
+	return l.contextFields(ctx)
+}
+
+// 附加 ctx 中字段的输出
+func (l *Logger) DebugCtx(ctx context.Context, args ...interface{}) {
+	l.log(DEBUG, message(args), l.levelContextFields(ctx, DEBUG)...)
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, args ...interface{}) {
+	l.log(INFO, message(args), l.levelContextFields(ctx, INFO)...)
+}
+
+func (l *Logger) NoticeCtx(ctx context.Context, args ...interface{}) {
+	l.log(NOTICE, message(args), l.levelContextFields(ctx, NOTICE)...)
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, args ...interface{}) {
+	l.log(WARN, message(args), l.levelContextFields(ctx, WARN)...)
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, args ...interface{}) {
+	l.log(ERROR, message(args), l.levelContextFields(ctx, ERROR)...)
+}
+
+func (l *Logger) CriticalCtx(ctx context.Context, args ...interface{}) {
+	l.log(CRITICAL, message(args), l.levelContextFields(ctx, CRITICAL)...)
+}
+
+func (l *Logger) FatalCtx(ctx context.Context, args ...interface{}) {
+	l.log(FATAL, message(args), l.levelContextFields(ctx, FATAL)...)
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// 差异日志的变更列表字段的键
+const ChangesKey = "changes"
+
+// 变更类型
+const (
+	ChangeAdded    = "+" // 新增
+	ChangeRemoved  = "-" // 删除
+	ChangeModified = "~" // 修改
+)
+
+// 一项变更
+type Change struct {
+	Key    string      `json:"key"`              // 键，嵌套的 map 以 "." 连接，如 "rotate.max_size"
+	Op     string      `json:"op"`               // 变更类型 +、-、~
+	Before interface{} `json:"before,omitempty"` // 变更前的值，新增时为 nil
+	After  interface{} `json:"after,omitempty"`  // 变更后的值，删除时为 nil
+}
+
+/*
+ * 配置或状态的差异日志
+ *
+ *   l.Diff("config reloaded", oldCfg, newCfg, logger.Field{Key: "by", Value: user})
+ *
+ * 文本输出为消息之后每项变更一列，新增为绿色 "+key=值"，删除为红色 "-key=值"，
+ * 修改为黄色 "~key: 旧值 -> 新值"；JSON 输出以 changes 字段输出变更数组，见 Change。
+ */
+type DiffEntry struct {
+	Message string   // 消息
+	Changes []Change // 按键排序的变更
+}
+
+/*
+ * 比较两个 map 并以 INFO 级别输出差异，嵌套的 map[string]interface{} 按键展开比较
+ * 没有变更时同样输出，便于确认操作已执行。fields 为附加字段
+ */
+func (l *Logger) Diff(msg string, before, after map[string]interface{}, fields ...Field) LogResult {
+	return l.log(INFO, &DiffEntry{Message: msg, Changes: Changes(before, after)}, fields...)
+}
+
+// 两个 map 的差异，按键排序，值按 reflect.DeepEqual 比较
+func Changes(before, after map[string]interface{}) []Change {
+	b, a := map[string]interface{}{}, map[string]interface{}{}
+	flattenMap(b, "", before)
+	flattenMap(a, "", after)
+
+	changes := []Change{}
+	for k, bv := range b {
+		av, ok := a[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: k, Op: ChangeRemoved, Before: bv})
+		case !reflect.DeepEqual(bv, av):
+			changes = append(changes, Change{Key: k, Op: ChangeModified, Before: bv, After: av})
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			changes = append(changes, Change{Key: k, Op: ChangeAdded, After: av})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// 展开嵌套的 map，键以 "." 连接
+func flattenMap(dst map[string]interface{}, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+			flattenMap(dst, k, sub)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// 带颜色后缀的数据列：消息之后每项变更一列
+func (d *DiffEntry) columns() []string {
+	cols := make([]string, 0, len(d.Changes)+1)
+	cols = append(cols, d.Message)
+	for _, c := range d.Changes {
+		switch c.Op {
+		case ChangeAdded:
+			cols = append(cols, fmt.Sprintf("+%s=%v-g", c.Key, c.After))
+		case ChangeRemoved:
+			cols = append(cols, fmt.Sprintf("-%s=%v-r", c.Key, c.Before))
+		default:
+			cols = append(cols, fmt.Sprintf("~%s: %v -> %v-y", c.Key, c.Before, c.After))
+		}
+	}
+	return cols
+}
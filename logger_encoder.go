@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"strings"
+	"time"
+)
+
+// 日志条目，由日志方法生成后交给编码器
+type Entry struct {
+	Level   LogType   // 日志级别
+	Time    time.Time // 日志时间
+	ID      string    // 日志条目ID，未开启时为空
+	Caller  *Caller   // 调用位置，未开启 SetReportCaller 时为 nil
+	Message string    // 文本消息
+	Columns []string  // 切片形式的数据列，可带颜色后缀(-g/-r/-b/-y)
+	Fields  []Field   // 附加字段
+
+	Access *AccessEntry // 访问日志，Columns 为其数据列，结构化编码器可按字段输出
+	Diff   *DiffEntry   // 差异日志，Columns 为其数据列，结构化编码器以 changes 字段输出
+}
+
+// 键值字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// 日志内容：访问日志为 "METHOD path"，切片去除颜色后缀后以" | "拼接，否则为 Message
+func (e *Entry) Text() string {
+	if e.Access != nil {
+		return e.Access.message()
+	}
+	if e.Diff != nil {
+		return e.Diff.Message
+	}
+	if e.Columns == nil {
+		return e.Message
+	}
+	cols := make([]string, len(e.Columns))
+	for j, s := range e.Columns {
+		cols[j] = trimColorSuffix(s)
+	}
+	return strings.Join(cols, " | ")
+}
+
+// 全部字段，访问日志与差异日志的字段在前
+func (e *Entry) AllFields() []Field {
+	extra := e.extraFields()
+	if extra == nil {
+		return e.Fields
+	}
+	return append(extra, e.Fields...)
+}
+
+// 访问日志与差异日志按字段输出的内容
+func (e *Entry) extraFields() []Field {
+	switch {
+	case e.Access != nil:
+		return e.Access.fields()
+	case e.Diff != nil:
+		return []Field{{Key: ChangesKey, Value: e.Diff.Changes}}
+	}
+	return nil
+}
+
+// 定义编码器接口，将日志条目编码为一行输出
+type Encoder interface {
+	Encode(e *Entry) ([]byte, error)
+}
+
+var (
+	// 声明接口实现者
+	_ BufferEncoder = &TextEncoder{}
+	_ BufferEncoder = &JSONEncoder{}
+)
+
+/*
+ * 文本编码器，输出以" | "分隔的列
+ * Color 为 true 时按级别和数据段颜色后缀输出终端颜色，否则去除颜色后缀
+ */
+type TextEncoder struct {
+	Color bool
+}
+
+func (enc *TextEncoder) Encode(e *Entry) ([]byte, error) {
+	buf := GetBuffer()
+	defer buf.Free()
+	if err := enc.EncodeTo(buf, e); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+func (enc *TextEncoder) EncodeTo(b *Buffer, e *Entry) error {
+	// 级别与时间
+	b.WriteString(LevelPrefix(e.Level, enc.Color))
+	b.AppendTime(e.Time, recordTimeLayout)
+	b.WriteString(" | ")
+	if e.ID != "" {
+		b.WriteString(e.ID)
+		b.WriteString(" | ")
+	}
+	if e.Caller != nil {
+		b.WriteString(e.Caller.String())
+		b.WriteString(" | ")
+	}
+
+	// 数据列
+	if e.Columns != nil {
+		for _, col := range e.Columns {
+			enc.writeColumn(b, col)
+		}
+	} else {
+		b.WriteString(e.Message)
+		b.WriteString(" | ")
+	}
+
+	// 附加字段以 key=value 列输出
+	for _, f := range e.Fields {
+		b.WriteString(f.Key)
+		b.WriteString("=")
+		b.AppendValue(f.Value)
+		b.WriteString(" | ")
+	}
+	b.WriteString("\n")
+
+	return nil
+}
+
+// 写入一列，处理颜色后缀
+func (enc *TextEncoder) writeColumn(b *Buffer, col string) {
+	text := trimColorSuffix(col)
+	if enc.Color && len(text) != len(col) {
+		b.WriteString("\033[")
+		b.WriteString(dataColor[col[len(col)-1:]])
+		b.WriteString("m")
+		b.WriteString(text)
+		b.WriteString("\033[0m | ")
+		return
+	}
+	b.WriteString(text)
+	b.WriteString(" | ")
+}
+
+// 去除数据段的颜色后缀(-g/-r/-b/-y)
+func trimColorSuffix(s string) string {
+	ls := len(s)
+	if ls >= 2 && s[ls-2] == '-' {
+		if _, ok := dataColor[s[ls-1:]]; ok {
+			return s[:ls-2]
+		}
+	}
+	return s
+}
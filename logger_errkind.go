@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"errors"
+	"reflect"
+)
+
+// 错误分类的字段名
+const ErrorKindKey = "error.kind"
+
+// 错误分类规则
+type errorKind struct {
+	kind  string
+	match func(error) bool
+}
+
+/*
+ * 注册错误分类，字段值为 error 的日志按注册顺序匹配，附加 error.kind 字段
+ *
+ *   l.AddErrorKind("timeout", context.DeadlineExceeded, func(err error) bool {
+ *       var ne net.Error
+ *       return errors.As(err, &ne) && ne.Timeout()
+ *   })
+ *   l.AddErrorKind("not_found", sql.ErrNoRows, fs.ErrNotExist)
+ *   l.AddErrorKind("validation", (*ValidationError)(nil))
+ *   l.Errorw("query failed", "err", err) // ... err=... error.kind=not_found
+ *
+ * target 可以是：
+ *   error            按 errors.Is 匹配
+ *   func(error) bool 自定义匹配
+ *   错误类型的指针    按 errors.As 匹配该类型，如 (*ValidationError)(nil) 匹配 *ValidationError，
+ *                    (*net.Error)(nil) 匹配实现 net.Error 接口的错误
+ *
+ * 只对第一个错误字段分类，已有 error.kind 字段时不再分类。派生日志使用根日志的分类规则。
+ */
+func (l *Logger) AddErrorKind(kind string, targets ...interface{}) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.AddErrorKind(kind, targets...)
+		return
+	}
+
+	kinds := make([]errorKind, 0, len(targets))
+	for _, target := range targets {
+		kinds = append(kinds, errorKind{kind: kind, match: errorMatcher(target)})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errorKinds = append(l.errorKinds[:len(l.errorKinds):len(l.errorKinds)], kinds...)
+}
+
+// 将分类目标转为匹配函数
+func errorMatcher(target interface{}) func(error) bool {
+	switch t := target.(type) {
+	case func(error) bool:
+		return t
+	case error:
+		if rt := reflect.TypeOf(t); rt.Kind() != reflect.Ptr || !reflect.ValueOf(t).IsNil() {
+			return func(err error) bool { return errors.Is(err, t) }
+		}
+	case nil:
+		panic("logger: nil error kind target")
+	}
+
+	// 类型指针，按 errors.As 匹配
+	rt := reflect.TypeOf(target)
+	if rt.Kind() == reflect.Ptr && rt.Elem().Kind() == reflect.Interface {
+		// 接口类型，如 (*net.Error)(nil)
+		rt = rt.Elem()
+	} else if rt.Kind() != reflect.Ptr || !rt.Implements(errorType) {
+		panic("logger: error kind target must be an error, func(error) bool or error type pointer, got " + rt.String())
+	}
+	return func(err error) bool {
+		return errors.As(err, reflect.New(rt).Interface())
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// 为第一个错误字段附加分类，调用方需持有 l.mu
+func (l *Logger) classifyErrors(fields []Field) []Field {
+	var first error
+	for _, f := range fields {
+		if f.Key == ErrorKindKey {
+			return fields
+		}
+		if err, ok := f.Value.(error); ok && first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		return fields
+	}
+
+	for _, k := range l.errorKinds {
+		if k.match(first) {
+			return append(fields[:len(fields):len(fields)], Field{Key: ErrorKindKey, Value: k.kind})
+		}
+	}
+	return fields
+}
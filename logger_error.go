@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// 设置错误处理函数，用于上报日志内部的异常(如写出失败、编码失败、写出停滞)
+// 后台写出失败时同样交给此函数上报而不会中断程序；未设置时错误输出到标准错误
+// 处理函数不在日志的锁内调用，可以通过本日志输出错误；持锁期间发生的错误在解锁后上报
+func (l *Logger) SetErrorHandler(handler func(error)) {
+	if l.noop() {
+		return
+	}
+
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+	l.errorHandler = handler
+}
+
+// 设置备用输出(如 os.Stderr)，主输出重试后仍写入失败时日志改写到备用输出，避免丢失
+func (l *Logger) SetFallbackOutput(w io.Writer) {
+	if l.noop() {
+		return
+	}
+
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+	l.fallback = w
+}
+
+// 上报内部错误，调用方不能持有 l.mu 与 outMu，持锁时使用 deferError
+func (l *Logger) handleError(err error) {
+	l.errMu.Lock()
+	handler := l.errorHandler
+	l.recordError(err)
+	l.errMu.Unlock()
+
+	reportError(handler, err)
+}
+
+// 暂存持锁期间发生的内部错误，由解锁后的 reportErrors 上报
+// 错误处理函数可能通过本日志输出，在锁内调用会死锁
+func (l *Logger) deferError(err error) {
+	l.errMu.Lock()
+	l.recordError(err)
+	l.deferred = append(l.deferred, err)
+	atomic.StoreInt32(&l.hasDeferred, 1)
+	l.errMu.Unlock()
+}
+
+// 上报暂存的内部错误，调用方不能持有 l.mu 与 outMu
+func (l *Logger) reportErrors() {
+	if atomic.LoadInt32(&l.hasDeferred) == 0 {
+		return
+	}
+	l.errMu.Lock()
+	errs, handler := l.deferred, l.errorHandler
+	l.deferred = nil
+	atomic.StoreInt32(&l.hasDeferred, 0)
+	l.errMu.Unlock()
+
+	for _, err := range errs {
+		reportError(handler, err)
+	}
+}
+
+// 在新的goroutine中上报，供消费goroutine使用：
+// 错误处理函数输出日志时可能需要消费goroutine排空队列(如内存降级模式)
+func (l *Logger) handleErrorAsync(err error) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.handleError(err)
+	}()
+}
+
+// 交给错误处理函数，未设置时输出到标准错误
+func reportError(handler func(error), err error) {
+	if handler != nil {
+		handler(err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// 业务事件名称字段的键
+const EventKey = "event"
+
+// 业务事件的去向
+type EventRoute int
+
+const (
+	EventsInclude EventRoute = iota // 与日志一同输出，默认
+	EventsOnly                      // 只输出业务事件
+	EventsExclude                   // 不输出业务事件
+)
+
+// 业务事件的消息，emit 据此识别事件
+type eventMsg string
+
+/*
+ * 输出一条业务事件，用于产品分析
+ *
+ *   l.Event("order_paid", logger.Field{Key: "order", Value: id}, logger.Field{Key: "amount", Value: 99.5})
+ *   // JSON: {"level":"INFO","msg":"order_paid","event":"order_paid","order":"A1001","amount":99.5}
+ *
+ * 事件以 INFO 级别、消息与 event 字段为事件名输出，与日志共用编码器与输出通路，但：
+ *   - 不受日志级别影响，只受事件级别控制，见 SetEventLevel
+ *   - 不经过重复日志采样、租户策略与采样优先级，不受派生日志的有效期限制
+ *   - 队列满时不论处理策略都等待入队，不会被丢弃；附加输出端的待写条数上限同样不适用
+ *
+ * 去向由 SetEventRoute(主输出)与 Sink.Events(附加输出端)控制，如只写入分析用的输出端：
+ *
+ *   l.SetEventRoute(logger.EventsExclude)
+ *   l.AddSink(&logger.Sink{Writer: analytics, Events: logger.EventsOnly, Encoder: logger.NewJSONEncoder()})
+ *
+ * 返回值与日志方法相同只反映主输出，主输出不接收事件时为 LogFiltered。
+ * 输出端故障时的积压上限(BacklogBytes)仍然有效，那是对外部故障的保护而非运维策略。
+ */
+func (l *Logger) Event(name string, fields ...Field) LogResult {
+	if l.noop() {
+		return LogClosed
+	}
+
+	root := l
+	if l.root != nil {
+		root = l.root
+	}
+	if root.eventLevel.Level() > INFO {
+		return LogFiltered
+	}
+
+	var caller *Caller
+	l.mu.Lock()
+	if l.reportCaller {
+		caller = newCaller(callerPC(1 + l.callerSkip))
+	}
+	fields = l.mergeFields(append([]Field{{Key: EventKey, Value: name}}, fields...))
+	eventName := l.name
+	if l.root != nil {
+		l.mu.Unlock()
+		root.mu.Lock()
+	}
+
+	if root.closed {
+		root.mu.Unlock()
+		return LogClosed
+	}
+	e, result := root.emit(eventName, time.Time{}, INFO, eventMsg(name), fields, caller)
+	hooks := root.hooks
+	root.mu.Unlock()
+	root.reportErrors()
+
+	if e != nil && len(hooks) > 0 {
+		root.fireHooks(hooks, e)
+	}
+	return result
+}
+
+/*
+ * 设置业务事件的最低级别，与日志级别(SetLogLevel)相互独立
+ * 事件为 INFO 级别，默认输出；设置为高于 INFO 的级别时不再输出事件。派生日志设置根日志
+ */
+func (l *Logger) SetEventLevel(level LogType) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetEventLevel(level)
+		return
+	}
+	l.eventLevel.SetLevel(level)
+}
+
+// 设置主输出的业务事件去向，默认 EventsInclude。派生日志设置根日志
+func (l *Logger) SetEventRoute(route EventRoute) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetEventRoute(route)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.eventRoute = route
+}
+
+// 按去向判断是否输出
+func (r EventRoute) accepts(event bool) bool {
+	switch r {
+	case EventsOnly:
+		return event
+	case EventsExclude:
+		return !event
+	}
+	return true
+}
+
+// 业务事件等待入队，不受处理策略影响
+func (l *Logger) enqueueEvent(q queued) {
+	l.queue <- q
+}
+
+// 业务事件写入附加输出端，不受待写条数上限影响
+func (s *Sink) enqueueEvent(q queued) {
+	s.mu.Lock()
+	s.pending = append(s.pending, q)
+	atomic.AddInt64(&s.pendingBytes, int64(q.buf.Len()))
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
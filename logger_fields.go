@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+ * 派生携带字段的日志
+ *
+ *   rl := l.With("request_id", id, "user", u)
+ *   rl.Info("done")
+ *
+ * 派生日志继承当前的级别和名称，可单独调整；输出通路(缓存、队列、编码器、输出)与根日志共用，
+ * 相关设置请在根日志上进行。自定义格式化函数(SetLoggerFormat)不支持字段，字段会被忽略。
+ */
+func (l *Logger) With(kvs ...interface{}) *Logger {
+	return l.WithFields(kvsToFields(kvs)...)
+}
+
+// 派生携带字段的日志
+func (l *Logger) WithFields(fields ...Field) *Logger {
+	if l.noop() {
+		return l
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	root := l.root
+	if root == nil {
+		root = l
+	}
+
+	child := &Logger{}
+	child.root = root
+	child.logLevel.SetLevel(l.logLevel.Level())
+	child.name = l.name
+	child.source = l.source
+	child.ttl = l.ttl
+	child.reportCaller = l.reportCaller
+	child.callerSkip = l.callerSkip
+	child.callerChains = l.callerChains
+	child.fields = make([]Field, 0, len(l.fields)+len(fields))
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+
+	return child
+}
+
+/*
+ * 派生带有效期的日志：日志在 ttl 内仍未写出(如输出积压)时直接丢弃，
+ * 适合进度等时效性强的日志，避免积压时挤占有价值的日志
+ *
+ *   progress := l.WithTTL(5 * time.Second)
+ *   progress.Info("import 50%")
+ */
+func (l *Logger) WithTTL(ttl time.Duration) *Logger {
+	if l.noop() {
+		return l
+	}
+
+	child := l.WithFields()
+	child.ttl = ttl
+	return child
+}
+
+// 带字段的输出
+func (l *Logger) Debugw(msg string, kvs ...interface{}) {
+	l.log(DEBUG, msg, kvsToFields(kvs)...)
+}
+
+func (l *Logger) Infow(msg string, kvs ...interface{}) {
+	l.log(INFO, msg, kvsToFields(kvs)...)
+}
+
+func (l *Logger) Noticew(msg string, kvs ...interface{}) {
+	l.log(NOTICE, msg, kvsToFields(kvs)...)
+}
+
+func (l *Logger) Warnw(msg string, kvs ...interface{}) {
+	l.log(WARN, msg, kvsToFields(kvs)...)
+}
+
+func (l *Logger) Errorw(msg string, kvs ...interface{}) {
+	l.log(ERROR, msg, kvsToFields(kvs)...)
+}
+
+func (l *Logger) Criticalw(msg string, kvs ...interface{}) {
+	l.log(CRITICAL, msg, kvsToFields(kvs)...)
+}
+
+func (l *Logger) Fatalw(msg string, kvs ...interface{}) {
+	l.log(FATAL, msg, kvsToFields(kvs)...)
+}
+
+// 将 key, value 交替的参数转为字段，可直接传入 Field
+func kvsToFields(kvs []interface{}) []Field {
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	fields := make([]Field, 0, (len(kvs)+1)/2)
+	for i := 0; i < len(kvs); i++ {
+		if f, ok := kvs[i].(Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+
+		key, ok := kvs[i].(string)
+		if !ok {
+			key = fmt.Sprint(kvs[i])
+		}
+		if i+1 >= len(kvs) {
+			// 缺少值
+			fields = append(fields, Field{Key: key, Value: "!MISSING"})
+			break
+		}
+		fields = append(fields, Field{Key: key, Value: kvs[i+1]})
+		i++
+	}
+	return fields
+}
@@ -1,63 +1,269 @@
 package logger
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime/debug"
+	"sync"
 	"time"
 )
 
 /*
  * 生成回滚日志实例
+ *
+ *   l := NewRotateFileLogger("./logs", WithMaxSize(100<<20), WithMaxAge(7*24*time.Hour), WithCompress(true))
  */
-func NewRotateFileLogger(dir string) *RotateFileLogger {
+func NewRotateFileLogger(dir string, opts ...RotateOption) *RotateFileLogger {
+	l, err := newRotateFileLogger(dir, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// 生成回滚日志实例，创建文件失败时返回错误
+func newRotateFileLogger(dir string, opts ...RotateOption) (*RotateFileLogger, error) {
 	// 设置日志的默认参数
 	l := &RotateFileLogger{}
+	l.Logger.init()
+	l.cache.use = false        // 文件默认使用队列
+	l.encoder = &TextEncoder{} // 文件不输出颜色
 	l.fileNameFormatFunc = l.DefaultFileNameFormat
-	l.logFormatFunc = l.DefaultLogFormatFunc
 	l.newFileGapTime = 0
-	l.lastFileTime = time.Now()
-	file, err := l.createLogFile(l.fileNameFormatFunc(l.lastFileTime))
+	l.dirPath = dir // 日志目录
+	for _, opt := range opts {
+		opt(l)
+	}
+	now := time.Now()
+	l.lastFileTime = alignFileTime(now, l.newFileGapTime)
+	filename := l.fileNameFormatFunc(l.lastFileTime)
+	if l.slice.interval > 0 {
+		filename = l.slice.begin(now, filename)
+	}
+	err := l.openFile(filename)
 	if err != nil {
-		panic(err)
-		return nil
+		return nil, err
 	}
-	l.file = file
-	l.dirPath = dir     // 日志目录
-	l.Logger.out = file // 设置输出
+	l.Logger.setOut(l) // 设置输出，经由 Write 按间隔和大小切换文件
 
-	return l
+	// 启动时清理一次过期文件
+	l.cleanup("")
+
+	return l, nil
 }
 
 // 一段时间自动创建新的log文件
 // 如果在间隔时间无日志，此间隔不会创建log文件
+// 可选按大小切换、压缩旧文件、按保留期限和数量清理，见 RotateOption
 type RotateFileLogger struct {
 	Logger                                      // 组合日志实例
 	file               *os.File                 // 正在操作文件
+	buf                *bufio.Writer            // 写入缓冲，WithBufferSize 开启
+	bufSize            int                      // 写入缓冲大小，0 表示直接写文件
+	fileEncoding       FileEncoding             // 文件的字符编码
+	encoded            []byte                   // 转码的缓冲，由输出锁保护
+	filePath           string                   // 正在操作文件的路径
+	size               int64                    // 正在操作文件的大小
 	dirPath            string                   // logs 文件所在文件夹
 	fileNameFormatFunc func(t time.Time) string // 获取文件名称格式
 	newFileGapTime     time.Duration            // 创建新log的间隔时间
 	lastFileTime       time.Time                // 上次创建文件，文件对应时间(依据间隔时间，不是真实创建时间)
+	slice              timeSlice                // 时间切片，WithTimeSlice 开启
+	policy             rotatePolicy             // 大小、压缩与保留策略
+	cleanMu            sync.Mutex               // 清理时的互斥锁
+	cleanWG            sync.WaitGroup           // 后台清理goroutine
+	refs               int                      // 共享的引用数，由输出锁保护
+	ownerClosed        bool                     // 自身已关闭，由输出锁保护
 }
 
 // 声明接口实现者
-var _ ILogger = &RotateFileLogger{}
+var (
+	_ StdLogger = &RotateFileLogger{}
+	_ Rotator   = &RotateFileLogger{}
+	_ io.Writer = &RotateFileLogger{}
+)
 
-func (l *RotateFileLogger) Start() {
+// 启动日志，ctx 取消时关闭日志和当前文件，见 Logger.Start
+func (l *RotateFileLogger) Start(ctx ...context.Context) {
 	// 初始化日志
-	l.Logger.Start()
+	l.Logger.start(ctx, l.Close)
 }
 
+// 关闭日志并关闭当前文件，等待后台压缩和清理结束。仍有共享(Share)时文件在共享全部关闭后关闭
+func (l *RotateFileLogger) Close() error {
+	err := l.Logger.Close()
+
+	// 仍有共享时由最后一个 SharedFile 关闭文件
+	l.outMu.Lock()
+	l.ownerClosed = true
+	err = joinErrors(err, l.releaseFile())
+	closed := l.file == nil
+	l.outMu.Unlock()
+
+	if closed {
+		l.cleanWG.Wait()
+	}
+	return err
+}
+
+// 立即切换新文件：写出缓存和队列后将当前文件改名备份并重新创建
+// 文件已随日志与全部共享关闭时返回 ErrOutputClosed
+func (l *RotateFileLogger) Rotate() error {
+	if err := l.Flush(); err != nil {
+		return err
+	}
+
+	defer l.reportErrors()
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("%w: %s", ErrOutputClosed, l.filePath)
+	}
+	if l.size == 0 {
+		return nil
+	}
+	return l.rotateBySize(time.Now())
+}
+
+// 设置按时间切换文件的间隔，如 time.Hour 或 24*time.Hour，切换时刻按本地时间对齐
 func (l *RotateFileLogger) SetNewFileGapTime(gapTime time.Duration) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
 	l.newFileGapTime = gapTime
+	l.lastFileTime = alignFileTime(l.lastFileTime, gapTime)
+}
+
+// 写入当前文件，超过间隔时间或大小上限时先切换新文件。由 Logger 在持有输出锁时调用
+func (l *RotateFileLogger) Write(p []byte) (int, error) {
+	// 是否生成新文件
+	now := time.Now()
+	gapTime := now.Sub(l.lastFileTime)
+	if filename, ok := l.slice.next(now); ok {
+		// 进入新的时间切片，不论大小都切换新文件
+		rotated := l.filePath
+		if err := l.openFile(filename); err != nil {
+			return 0, err
+		}
+		l.cleanup(rotated)
+	} else if gapTime > l.newFileGapTime && l.newFileGapTime > 0 && l.slice.interval == 0 {
+		rate := int(int64(gapTime) / int64(l.newFileGapTime))
+		l.lastFileTime = l.lastFileTime.Add(l.newFileGapTime * time.Duration(rate))
+
+		// 旧文件保留原名作为备份
+		rotated := l.filePath
+		if err := l.openFile(l.fileNameFormatFunc(l.lastFileTime)); err != nil {
+			return 0, err
+		}
+		l.cleanup(rotated)
+	} else if l.policy.maxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.policy.maxSize {
+		// 超过大小上限，当前文件改名备份后重新创建
+		if err := l.rotateBySize(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.writeFile(p)
+	l.size += int64(n)
+	if l.fileEncoding == FileUTF16LE && err == nil {
+		// 调用方按转码前的长度检查写入是否完整
+		n = len(p)
+	}
+	return n, err
+}
+
+// 按文件编码转码后写入缓冲或文件，返回写入文件的字节数
+func (l *RotateFileLogger) writeFile(p []byte) (int, error) {
+	p = l.fileEncoding.encode(l.encoded, p)
+	if l.fileEncoding == FileUTF16LE {
+		l.encoded = p
+	}
+	if l.buf != nil {
+		return l.buf.Write(p)
+	}
+	return l.file.Write(p)
+}
+
+// 将写入缓冲写到文件。由 Logger 在队列空闲和排空后持有输出锁调用
+func (l *RotateFileLogger) flushBuffer() error {
+	if l.buf == nil || l.file == nil {
+		return nil
+	}
+	return l.buf.Flush()
+}
+
+// 写出缓冲并同步到磁盘后关闭当前文件
+// next 为切换后继续写入的文件名，开启 WithRotateMarker 时在末尾写入标记行，关闭日志时为空
+func (l *RotateFileLogger) closeFile(next string) error {
+	var err error
+	if l.buf != nil {
+		err = l.buf.Flush()
+	}
+	if err == nil && next != "" && l.policy.marker {
+		_, err = l.file.Write(l.fileEncoding.encode(nil, []byte(rotateMarkerLine(next, time.Now()))))
+	}
+	if err == nil {
+		err = l.file.Sync()
+	}
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// 打开(创建)文件作为当前文件，并关闭旧文件
+func (l *RotateFileLogger) openFile(filename string) error {
+	file, err := l.createLogFile(filename)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if l.file != nil {
+		// 缓冲写出失败时其中的日志已丢失，上报后继续使用新文件
+		if err := l.closeFile(filepath.Base(file.Name())); err != nil {
+			l.deferError(err)
+		}
+	}
+	l.file = file
+	if l.bufSize > 0 {
+		if l.buf == nil {
+			l.buf = bufio.NewWriterSize(file, l.bufSize)
+		} else {
+			l.buf.Reset(file)
+		}
+	}
+	l.filePath = file.Name()
+	l.size = info.Size()
+	if bom := l.fileEncoding.bom(); bom != nil && l.size == 0 {
+		// 新文件开头写入 BOM，追加到已有文件时不写
+		n, err := file.Write(bom)
+		l.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // 默认 创建格式化的文件名. fileTime 不一定是创建文件的时间
 func (l *RotateFileLogger) DefaultFileNameFormat(fileTime time.Time) string {
 	// tips: linux 不支持 2006-01-02 15:04:05.999 ":"名称
 	// tips: os x  2006/01-02 15-04-05-999 带有"/"报no such file or directory
+	// 切换间隔小于一天时文件名精确到小时或分钟，避免不同时段写入同一文件
 	layout := "2006-01-02"
+	if l.newFileGapTime > 0 && l.newFileGapTime < time.Hour {
+		layout = "2006-01-02-15-04"
+	} else if l.newFileGapTime > 0 && l.newFileGapTime < 24*time.Hour {
+		layout = "2006-01-02-15"
+	}
 	formatTime := fileTime.Format(layout)
 	if len(formatTime) != len(layout) {
 		// 对于如果是 出现2006-01-02 15:04:05.99  适配处理 成2006-01-02 15:04:05.990
@@ -72,7 +278,7 @@ func (l *RotateFileLogger) createLogFile(filename string) (*os.File, error) {
 		filename = l.dirPath + "/" + filename
 		err := os.MkdirAll(l.dirPath, 0777)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 	}
 
@@ -83,32 +289,17 @@ func (l *RotateFileLogger) createLogFile(filename string) (*os.File, error) {
 	return file, nil
 }
 
-func (l *RotateFileLogger) DefaultLogFormatFunc(logType LogType, i interface{}) (string, []interface{}, bool) {
-	// 异常处理
+func (l *RotateFileLogger) DefaultLogFormatFunc(logType LogType, i interface{}) (_ string, _ []interface{}, isLog bool) {
+	// 异常处理，上报后丢弃该条日志
 	defer func() {
 		e := recover()
 		if e != nil {
-			panic(debug.Stack())
+			l.deferError(fmt.Errorf("logger: format panic: %v\n%s", e, debug.Stack()))
+			isLog = false
 		}
 	}()
 
-	// 是否生成新文件
-	now := time.Now()
-	gapTime := now.Sub(l.lastFileTime)
-	if gapTime > l.newFileGapTime && l.newFileGapTime > 0 {
-		l.file.Close()
-
-		rate := int(int64(gapTime) / int64(l.newFileGapTime))
-		l.lastFileTime = l.lastFileTime.Add(l.newFileGapTime * time.Duration(rate))
-		file, err := l.createLogFile(l.fileNameFormatFunc(l.lastFileTime))
-		if err != nil {
-			file.Close()
-			panic(err)
-		}
-
-		l.file = file
-		l.Logger.out = file
-	}
+	now := l.now()
 
 	// 计算日期格式
 	layout := "2006/01/02 - 15:04:05.9999"
@@ -121,6 +312,11 @@ func (l *RotateFileLogger) DefaultLogFormatFunc(logType LogType, i interface{})
 		formatTime += ".000"[4-(len(layout)-len(formatTime)) : 4]
 	}
 
+	// 附加日志条目ID
+	if id := l.newEntryID(); id != "" {
+		formatTime += " | " + id
+	}
+
 	// 计算数据format
 	format := "[ %s ] %s | "
 	// b := make([]byte, 0, 1024)
@@ -166,9 +362,3 @@ func (l *RotateFileLogger) DefaultLogFormatFunc(logType LogType, i interface{})
 
 	return format, values, true
 }
-
-// 兼容gorm日志实现Print
-func (l *RotateFileLogger) Print(v interface{}) {
-	// @Todo...
-	panic("method not implement")
-}
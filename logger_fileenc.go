@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 日志文件的字符编码
+type FileEncoding int
+
+const (
+	FileUTF8    = FileEncoding(0) // UTF-8，不带 BOM(默认)
+	FileUTF8BOM = FileEncoding(1) // UTF-8，新文件开头写入 BOM
+	FileUTF16LE = FileEncoding(2) // UTF-16LE，新文件开头写入 BOM
+)
+
+var fileEncodingNames = map[FileEncoding]string{
+	FileUTF8:    "utf8",
+	FileUTF8BOM: "utf8-bom",
+	FileUTF16LE: "utf16le",
+}
+
+func (enc FileEncoding) String() string {
+	if name, ok := fileEncodingNames[enc]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// 由名称解析文件编码，空串为 FileUTF8
+func ParseFileEncoding(s string) (FileEncoding, error) {
+	if s == "" {
+		return FileUTF8, nil
+	}
+	for enc, name := range fileEncodingNames {
+		if name == s {
+			return enc, nil
+		}
+	}
+	return FileUTF8, fmt.Errorf("logger: unknown file encoding %q", s)
+}
+
+/*
+ * 设置日志文件的字符编码，供会误判编码的旧版 Windows 工具读取
+ *
+ *   l := NewRotateFileLogger("./logs", WithFileEncoding(logger.FileUTF16LE))
+ *
+ * 新建(空)文件开头写入 BOM；UTF-16LE 在写入时转码，大小上限按转码前的字节数估算。
+ * ParseRecord、Summarize 等只读取 UTF-8 文件。
+ */
+func WithFileEncoding(enc FileEncoding) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.fileEncoding = enc
+	}
+}
+
+// 编码的 BOM
+func (enc FileEncoding) bom() []byte {
+	switch enc {
+	case FileUTF8BOM:
+		return []byte{0xEF, 0xBB, 0xBF}
+	case FileUTF16LE:
+		return []byte{0xFF, 0xFE}
+	}
+	return nil
+}
+
+// 按编码转换写入的内容，UTF-8 原样返回。dst 为可复用的缓冲
+func (enc FileEncoding) encode(dst, p []byte) []byte {
+	if enc != FileUTF16LE {
+		return p
+	}
+	dst = dst[:0]
+	for len(p) > 0 {
+		r, size := utf8.DecodeRune(p)
+		p = p[size:]
+		if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+			dst = append(dst, byte(r1), byte(r1>>8), byte(r2), byte(r2>>8))
+			continue
+		}
+		dst = append(dst, byte(r), byte(r>>8))
+	}
+	return dst
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// 共享的回滚文件，见 RotateFileLogger.Share
+type SharedFile struct {
+	f    *RotateFileLogger
+	once sync.Once
+}
+
+// 声明接口实现者
+var (
+	_ io.WriteCloser = &SharedFile{}
+	_ bufferedWriter = &SharedFile{}
+)
+
+/*
+ * 共享回滚文件，返回的 SharedFile 作为其他日志的输出，与文件自身的日志串行写入
+ *
+ *   file := logger.NewRotateFileLogger("./logs")
+ *   file.Start()
+ *   api := file.Facade("api")
+ *   api.SetLogLevel(logger.INFO)
+ *   api.Start()
+ *   db := file.Facade("db")
+ *   db.Start()
+ *
+ * 共享按引用计数：每个 SharedFile 关闭时释放一个引用，RotateFileLogger 自身的 Close 只关闭其日志，
+ * 文件在自身与全部共享都关闭后才关闭，先关闭的一方不会使其他日志写入失败。
+ * 按时间、大小切换与清理仍由文件统一处理。
+ */
+func (l *RotateFileLogger) Share() *SharedFile {
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+	l.refs++
+	return &SharedFile{f: l}
+}
+
+/*
+ * 创建写入共享文件的日志，级别、字段与名称等各自独立
+ * 日志关闭时释放共享，需调用 Start 启动
+ */
+func (l *RotateFileLogger) Facade(name string) *Logger {
+	s := l.Share()
+	f := NewLogger()
+	f.cache.use = false        // 同文件使用队列
+	f.encoder = &TextEncoder{} // 文件不输出颜色
+	f.name = name
+	f.setOut(s)
+	f.closers = append(f.closers, s)
+	return f
+}
+
+// 写入共享文件，文件已关闭时返回 ErrOutputClosed
+func (s *SharedFile) Write(p []byte) (int, error) {
+	l := s.f
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+	if l.file == nil {
+		return 0, fmt.Errorf("%w: %s", ErrOutputClosed, l.filePath)
+	}
+	return l.Write(p)
+}
+
+// 写出文件的写入缓冲
+func (s *SharedFile) flushBuffer() error {
+	l := s.f
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+	return l.flushBuffer()
+}
+
+// 释放共享，最后一个引用释放且文件自身已关闭时关闭文件。重复调用无效
+func (s *SharedFile) Close() error {
+	var err error
+	s.once.Do(func() {
+		l := s.f
+		l.outMu.Lock()
+		l.refs--
+		err = l.releaseFile()
+		closed := l.file == nil
+		l.outMu.Unlock()
+		if closed {
+			l.cleanWG.Wait()
+		}
+	})
+	return err
+}
+
+// 文件自身已关闭且没有共享时关闭当前文件。调用方需持有 l.outMu
+func (l *RotateFileLogger) releaseFile() error {
+	if !l.ownerClosed || l.refs > 0 || l.file == nil {
+		return nil
+	}
+	err := l.closeFile("")
+	if err != nil {
+		err = fmt.Errorf("logger: close %s: %w", l.file.Name(), err)
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,64 @@
+package logger
+
+import "fmt"
+
+/*
+ * 钩子，将指定级别的日志转发到外部系统，如将 CRITICAL/FATAL 发往 Sentry 或告警 webhook
+ *
+ *   l.AddHook(&alertHook{})
+ *
+ * Fire 在日志调用的goroutine中、日志锁之外同步执行，与正常的写出相互独立；
+ * 耗时的钩子应自行异步处理。Fire 返回的错误交给错误处理函数(SetErrorHandler)。
+ */
+type Hook interface {
+	Levels() []LogType       // 触发的级别
+	Fire(entry *Entry) error // 处理一条日志
+}
+
+// 添加钩子。派生日志添加到根日志
+func (l *Logger) AddHook(h Hook) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.AddHook(h)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 复制后追加，已取出的钩子列表不受影响
+	l.hooks = append(l.hooks[:len(l.hooks):len(l.hooks)], h)
+}
+
+// 执行匹配级别的钩子
+func (l *Logger) fireHooks(hooks []Hook, e *Entry) {
+	for _, h := range hooks {
+		if !hookFires(h, e.Level) {
+			continue
+		}
+		if err := fireHook(h, e); err != nil {
+			l.handleError(fmt.Errorf("logger: hook %T: %w", h, err))
+		}
+	}
+}
+
+// 执行钩子，钩子 panic 时转为错误
+func fireHook(h Hook, e *Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.Fire(e)
+}
+
+func hookFires(h Hook, level LogType) bool {
+	for _, lv := range h.Levels() {
+		if lv == level {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 定义ID生成器接口，用于请求/关联ID及日志条目ID
+type IDGenerator interface {
+	NewID() string
+}
+
+// 函数适配为ID生成器
+type IDGeneratorFunc func() string
+
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+var (
+	// ULID 使用的 Crockford base32 字符表
+	crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// 默认ID生成器
+	defaultIDGenerator = NewULIDGenerator()
+
+	// 声明接口实现者
+	_ IDGenerator = &uuidV4Generator{}
+	_ IDGenerator = &uuidV7Generator{}
+	_ IDGenerator = &ulidGenerator{}
+	_ IDGenerator = &snowflakeGenerator{}
+)
+
+/*
+ * 创建 UUIDv4 生成器(完全随机)
+ */
+func NewUUIDv4Generator() IDGenerator {
+	return &uuidV4Generator{}
+}
+
+type uuidV4Generator struct{}
+
+func (g *uuidV4Generator) NewID() string {
+	var b [16]byte
+	randRead(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant RFC 4122
+	return formatUUID(b)
+}
+
+/*
+ * 创建 UUIDv7 生成器(毫秒时间戳前缀，可按时间排序)
+ */
+func NewUUIDv7Generator() IDGenerator {
+	return &uuidV7Generator{}
+}
+
+type uuidV7Generator struct{}
+
+func (g *uuidV7Generator) NewID() string {
+	var b [16]byte
+	randRead(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	b[6] = (b[6] & 0x0f) | 0x70 // version 7
+	b[8] = (b[8] & 0x3f) | 0x80 // variant RFC 4122
+	return formatUUID(b)
+}
+
+/*
+ * 创建 ULID 生成器
+ * 同一毫秒内随机部分单调递增，保证同进程生成的ID严格有序
+ */
+func NewULIDGenerator() IDGenerator {
+	return &ulidGenerator{}
+}
+
+type ulidGenerator struct {
+	mu     sync.Mutex
+	lastMs uint64   // 上次生成的毫秒时间戳
+	last   [10]byte // 上次生成的随机部分
+}
+
+func (g *ulidGenerator) NewID() string {
+	g.mu.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.lastMs {
+		// 同一毫秒(或时钟回拨)时沿用上次时间戳并递增随机部分
+		ms = g.lastMs
+		for i := len(g.last) - 1; i >= 0; i-- {
+			g.last[i]++
+			if g.last[i] != 0 {
+				break
+			}
+		}
+	} else {
+		g.lastMs = ms
+		randRead(g.last[:])
+	}
+	var b [16]byte
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	copy(b[6:], g.last[:])
+	g.mu.Unlock()
+
+	return encodeULID(b)
+}
+
+/*
+ * 创建 snowflake 生成器
+ * 41位毫秒时间戳(自 epoch 起) + 10位节点号 + 12位序列号
+ */
+func NewSnowflakeGenerator(node int64, epoch time.Time) IDGenerator {
+	if node < 0 || node > 1023 {
+		panic("logger: snowflake node must be between 0 and 1023")
+	}
+	return &snowflakeGenerator{node: node, epoch: epoch.UnixMilli()}
+}
+
+type snowflakeGenerator struct {
+	mu     sync.Mutex
+	node   int64 // 节点号
+	epoch  int64 // 起始时间(毫秒)
+	lastMs int64 // 上次生成的毫秒时间戳
+	seq    int64 // 当前毫秒内的序列号
+}
+
+func (g *snowflakeGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Now().UnixMilli() - g.epoch
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		g.seq = (g.seq + 1) & 0xfff
+		if g.seq == 0 {
+			// 序列号用尽，借用下一毫秒
+			ms++
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastMs = ms
+
+	return strconv.FormatInt(ms<<22|g.node<<12|g.seq, 10)
+}
+
+// 读取随机数，系统随机源不可用时退化为时间种子
+func randRead(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		binary.BigEndian.PutUint64(b[len(b)-8:], uint64(time.Now().UnixNano()))
+	}
+}
+
+// 格式化为 8-4-4-4-12 形式
+func formatUUID(b [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+// 按 Crockford base32 将128位编码为26个字符
+func encodeULID(b [16]byte) string {
+	var buf [26]byte
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+	// 128位共需26个5位组，首字符仅使用高3位
+	for i := 25; i >= 0; i-- {
+		buf[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
+
+// 设置日志使用的ID生成器，默认使用 ULID。派生日志设置根日志
+func (l *Logger) SetIDGenerator(g IDGenerator) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetIDGenerator(g)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.idGenerator = g
+}
+
+// 获取日志使用的ID生成器，可用于生成请求/关联ID
+func (l *Logger) GetIDGenerator() IDGenerator {
+	if l.noop() {
+		return nil
+	}
+
+	if l.root != nil {
+		return l.root.GetIDGenerator()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.idGenerator == nil {
+		return defaultIDGenerator
+	}
+	return l.idGenerator
+}
+
+// 设置是否为每条日志附加唯一ID
+// 默认使用 ULID，可排序，便于下游对至少一次投递产生的重复日志去重或在工单中引用单条日志
+// ID 由根日志生成，派生日志设置根日志，对根日志与全部派生日志生效
+func (l *Logger) SetEntryID(enable bool) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetEntryID(enable)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entryID = enable
+}
+
+// 生成日志条目ID，未开启时返回空串。调用方需持有 l.mu
+func (l *Logger) newEntryID() string {
+	if !l.entryID {
+		return ""
+	}
+	if l.idGenerator == nil {
+		return defaultIDGenerator.NewID()
+	}
+	return l.idGenerator.NewID()
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ * JSON 输出的 schema 版本
+ *
+ * 字段名变更时递增版本号并在此登记，旧版本的字段表保留在 jsonSchemas 中，
+ * 消费方可以通过 schema_version 字段区分新旧格式，或通过 JSONEncoder.SchemaVersion 固定输出旧格式。
+ *
+ * 变更记录:
+ *   1: 初始版本，字段 schema_version / level / ts / msg
+ *   2: 新增可选字段 id(开启 SetEntryID 时输出)
+ *   3: 附加字段以顶层键输出，与保留字段重名时加 "fields." 前缀
+ *   4: 新增可选字段 caller / func(开启 SetReportCaller 时输出)
+ *   5: 新增可选字段 severity(设置 JSONEncoder.Severity 时输出)，附加字段与其重名时加 "fields." 前缀
+ */
+const SchemaVersion = 5
+
+// JSON 各版本的字段名
+type jsonSchema struct {
+	Version  string // schema 版本字段
+	Level    string // 日志级别字段
+	Time     string // 时间字段
+	Message  string // 日志内容字段
+	ID       string // 日志条目ID字段，为空表示该版本不输出
+	Fields   bool   // 是否输出附加字段
+	Caller   string // 调用位置字段，为空表示该版本不输出
+	Func     string // 调用函数字段
+	Severity string // 严重性名称字段，为空表示该版本不输出
+}
+
+var jsonSchemas = map[int]jsonSchema{
+	1: {Version: "schema_version", Level: "level", Time: "ts", Message: "msg"},
+	2: {Version: "schema_version", Level: "level", Time: "ts", Message: "msg", ID: "id"},
+	3: {Version: "schema_version", Level: "level", Time: "ts", Message: "msg", ID: "id", Fields: true},
+	4: {Version: "schema_version", Level: "level", Time: "ts", Message: "msg", ID: "id", Fields: true, Caller: "caller", Func: "func"},
+	5: {Version: "schema_version", Level: "level", Time: "ts", Message: "msg", ID: "id", Fields: true, Caller: "caller", Func: "func", Severity: SeverityKey},
+}
+
+/*
+ * 创建JSON编码器，使用最新的schema版本
+ */
+func NewJSONEncoder() *JSONEncoder {
+	return &JSONEncoder{}
+}
+
+// JSON编码器，每条日志输出为一行JSON对象
+type JSONEncoder struct {
+	SchemaVersion int // 输出使用的schema版本(兼容模式)，0 表示最新版本
+
+	// 非 nil 时在级别之后以 severity 字段输出对应的严重性名称，如 GCPSeverities。schema 版本 5 起输出
+	Severity *SeverityProfile
+}
+
+func (enc *JSONEncoder) Encode(e *Entry) ([]byte, error) {
+	buf := GetBuffer()
+	defer buf.Free()
+	if err := enc.EncodeTo(buf, e); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+func (enc *JSONEncoder) EncodeTo(b *Buffer, e *Entry) error {
+	version := enc.SchemaVersion
+	if version == 0 {
+		version = SchemaVersion
+	}
+	schema, ok := jsonSchemas[version]
+	if !ok {
+		return fmt.Errorf("logger: unknown json schema version %d", version)
+	}
+
+	msg := e.Text()
+
+	b.WriteString("{")
+	writeJSONField(b, schema.Version, version, true)
+	writeJSONField(b, schema.Level, strings.TrimSpace(logTypeStrings[e.Level]), false)
+	if schema.Severity != "" && enc.Severity != nil {
+		writeJSONField(b, schema.Severity, enc.Severity.Text(e.Level), false)
+	}
+	writeJSONKey(b, schema.Time, false)
+	b.WriteByte('"')
+	b.AppendTime(e.Time, time.RFC3339Nano)
+	b.WriteByte('"')
+	if schema.ID != "" && e.ID != "" {
+		writeJSONField(b, schema.ID, e.ID, false)
+	}
+	if schema.Caller != "" && e.Caller != nil {
+		writeJSONField(b, schema.Caller, e.Caller.String(), false)
+		writeJSONField(b, schema.Func, e.Caller.Function, false)
+	}
+	writeJSONField(b, schema.Message, msg, false)
+	if schema.Fields {
+		for _, f := range e.extraFields() {
+			writeJSONField(b, f.Key, f.Value, false)
+		}
+		for _, f := range e.Fields {
+			key := f.Key
+			if schema.reserved(key) || (enc.Severity != nil && key == schema.Severity) {
+				key = "fields." + key
+			}
+			writeJSONField(b, key, f.Value, false)
+		}
+	}
+	b.WriteString("}\n")
+
+	return nil
+}
+
+// 是否为保留字段名
+func (s jsonSchema) reserved(key string) bool {
+	if key == "" {
+		return false
+	}
+	return key == s.Version || key == s.Level || key == s.Time || key == s.Message || key == s.ID ||
+		key == s.Caller || key == s.Func
+}
+
+// 写入一个JSON键值对，字符串、整数和布尔值不经过 json.Marshal
+func writeJSONField(b *Buffer, key string, value interface{}, first bool) {
+	writeJSONKey(b, key, first)
+	switch v := value.(type) {
+	case string:
+		b.b = appendJSONString(b.b, v)
+	case error:
+		b.b = appendJSONString(b.b, v.Error())
+	case int:
+		b.AppendInt(int64(v))
+	case int64:
+		b.AppendInt(v)
+	case bool:
+		b.b = strconv.AppendBool(b.b, v)
+	default:
+		j, err := json.Marshal(value)
+		if err != nil {
+			j, _ = json.Marshal(fmt.Sprint(value))
+		}
+		b.Write(j)
+	}
+}
+
+// 写入JSON键及冒号
+func writeJSONKey(b *Buffer, key string, first bool) {
+	if !first {
+		b.WriteByte(',')
+	}
+	b.b = appendJSONString(b.b, key)
+	b.WriteByte(':')
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// 写出延迟约定
+type latency struct {
+	limit      int64 // 最大写出延迟(纳秒)，0 表示不检查
+	violations int64 // 超出约定的批次数
+	reported   int64 // 已告警的批次数
+	worst      int64 // 上次告警以来观察到的最大延迟(纳秒)
+}
+
+/*
+ * 设置写出延迟约定，0 表示不检查
+ *
+ * 顺序与延迟约定：
+ *   1. 同一goroutine先后输出的日志按调用顺序写出(缓存与队列两种模式、运行中切换模式、Flush 均保持顺序)；
+ *   2. 正常负载下日志在 limit 内写出，缓存模式需保证 limit 大于缓存刷新周期。
+ *
+ * 后台检查每秒进行一次，发现超出约定时输出一条 WARN 日志，超出次数可通过 Stats 查看。
+ */
+func (l *Logger) SetMaxLatency(limit time.Duration) {
+	if l.noop() {
+		return
+	}
+
+	atomic.StoreInt64(&l.latency.limit, int64(limit))
+}
+
+// 未开启延迟约定时返回零值，避免每条日志额外取时间
+func (l *Logger) enqueueTime() time.Time {
+	if atomic.LoadInt64(&l.latency.limit) == 0 {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// 记录一批日志的写出延迟，at 为该批最早一条的入队时间
+func (l *Logger) observe(at time.Time) {
+	limit := atomic.LoadInt64(&l.latency.limit)
+	if limit == 0 || at.IsZero() {
+		return
+	}
+
+	d := int64(time.Since(at))
+	if d <= limit {
+		return
+	}
+	atomic.AddInt64(&l.latency.violations, 1)
+	for {
+		worst := atomic.LoadInt64(&l.latency.worst)
+		if d <= worst || atomic.CompareAndSwapInt64(&l.latency.worst, worst, d) {
+			break
+		}
+	}
+}
+
+// 后台检查延迟约定与写出停滞，输出采样汇总
+func (l *Logger) watchdog() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.checkLatency()
+			l.checkStall()
+			l.flushSamples(false)
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// 发现超出约定时输出告警
+func (l *Logger) checkLatency() {
+	limit := atomic.LoadInt64(&l.latency.limit)
+	if limit == 0 {
+		return
+	}
+
+	violations := atomic.LoadInt64(&l.latency.violations)
+	reported := atomic.SwapInt64(&l.latency.reported, violations)
+	if violations == reported {
+		return
+	}
+	worst := atomic.SwapInt64(&l.latency.worst, 0)
+	l.Warnw("logger: write latency exceeded limit",
+		"batches", violations-reported,
+		"limit", time.Duration(limit).String(),
+		"worst", time.Duration(worst).String())
+}
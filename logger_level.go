@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+/*
+ * 可并发读写的日志级别，零值为 DEBUG
+ *
+ * 同时是 http.Handler，用于在运行中调整级别而无需重启：
+ *
+ *   http.Handle("/log/level", l.LevelHandler())
+ *
+ *   curl localhost:8080/log/level                          # {"level":"INFO"}
+ *   curl -X PUT -d '{"level":"DEBUG"}' localhost:8080/log/level
+ */
+type AtomicLevel struct {
+	v int64
+}
+
+// 创建级别
+func NewAtomicLevel(level LogType) *AtomicLevel {
+	a := &AtomicLevel{}
+	a.SetLevel(level)
+	return a
+}
+
+// 当前级别
+func (a *AtomicLevel) Level() LogType {
+	return LogType(atomic.LoadInt64(&a.v))
+}
+
+// 设置级别
+func (a *AtomicLevel) SetLevel(level LogType) {
+	atomic.StoreInt64(&a.v, int64(level))
+}
+
+// 级别的 JSON 表示
+type levelPayload struct {
+	Level string `json:"level"`
+}
+
+// GET 返回当前级别；PUT 以 JSON({"level":"DEBUG"})或表单(level=DEBUG)设置级别
+func (a *AtomicLevel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var p levelPayload
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			p.Level = r.FormValue("level")
+		} else if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			adminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		level, err := ParseLogType(p.Level)
+		if err != nil {
+			adminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		a.SetLevel(level)
+	default:
+		adminError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	json.NewEncoder(w).Encode(levelPayload{Level: strings.TrimSpace(GetLogTypeString(a.Level()))})
+}
+
+// 返回调整本日志级别的 http.Handler
+func (l *Logger) LevelHandler() http.Handler {
+	if l.noop() {
+		return http.NotFoundHandler()
+	}
+
+	return &l.logLevel
+}
+
+// 从环境变量读取级别(如 LOG_LEVEL=INFO)，变量未设置时不修改
+func (l *Logger) SetLogLevelFromEnv(key string) error {
+	if l.noop() {
+		return nil
+	}
+
+	s, ok := os.LookupEnv(key)
+	if !ok || s == "" {
+		return nil
+	}
+	level, err := ParseLogType(s)
+	if err != nil {
+		return fmt.Errorf("logger: %s: %w", key, err)
+	}
+	l.SetLogLevel(level)
+	return nil
+}
+
+/*
+ * 收到信号(默认 SIGHUP)时通过 load 重新读取级别，返回停止监听的函数
+ *
+ *   stop := l.ReloadLevelOnSignal(func() (string, error) {
+ *       b, err := os.ReadFile("/etc/app/log_level")
+ *       return string(b), err
+ *   })
+ *   defer stop()
+ *
+ * 读取或解析失败时保持原级别，错误交给错误处理函数。stop 可重复调用。
+ */
+func (l *Logger) ReloadLevelOnSignal(load func() (string, error), sigs ...os.Signal) (stop func()) {
+	if l.noop() {
+		return func() {}
+	}
+
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		for {
+			select {
+			case <-ch:
+				s, err := load()
+				if err == nil {
+					var level LogType
+					if level, err = ParseLogType(s); err == nil {
+						l.SetLogLevel(level)
+						continue
+					}
+				}
+				l.handleError(fmt.Errorf("logger: reload level: %w", err))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
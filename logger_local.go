@@ -0,0 +1,249 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// 本地缓冲默认的交接间隔
+const defaultLocalInterval = time.Second
+
+// 本地缓冲的设置与登记，由根日志持有
+type localBuffers struct {
+	mu       sync.Mutex
+	size     int           // 交接的字节数阈值，0 表示未开启
+	interval time.Duration // 交接的时间阈值
+	buffers  map[*LocalBuffer]struct{}
+}
+
+/*
+ * 开启生产者本地缓冲(实验性)，size 为交接的字节数阈值，0 表示关闭
+ *
+ *   l.SetLocalBuffers(64<<10, time.Second)
+ *   go func() {
+ *       lb := l.NewLocalBuffer() // 每个生产者goroutine一个
+ *       defer lb.Close()
+ *       for job := range jobs {
+ *           lb.Infow("job done", "id", job.ID)
+ *       }
+ *   }()
+ *
+ * 开启后 LocalBuffer 的日志编码到各自的缓冲中，不经过日志锁；缓冲超过 size 字节、距上次交接
+ * 超过 interval(0 使用 1 秒)或调用 Flush、Close 时整批交给主输出，每批只加锁一次。
+ * 数千个goroutine同时输出日志时可消除日志锁上的竞争。
+ *
+ * 代价：
+ *   - 同一缓冲内的日志保持顺序，不同缓冲之间按批交错，主输出中的日志不再严格按时间排序
+ *   - 只写主输出，不经过附加输出端、钩子、采样、租户策略与错误分类，不记录调用位置
+ *   - 编码器、颜色、字段与来源在创建缓冲时确定，之后的修改对已创建的缓冲无效
+ *   - 编码器会被多个goroutine同时调用，自定义编码器需可并发使用
+ *
+ * 未开启时 LocalBuffer 的方法等同于日志的对应方法，可先在代码中使用再按需开启。
+ */
+func (l *Logger) SetLocalBuffers(size int, interval time.Duration) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetLocalBuffers(size, interval)
+		return
+	}
+
+	if interval <= 0 {
+		interval = defaultLocalInterval
+	}
+	l.locals.mu.Lock()
+	defer l.locals.mu.Unlock()
+	l.locals.size = size
+	l.locals.interval = interval
+}
+
+// 生产者本地缓冲，由一个goroutine使用，见 SetLocalBuffers
+type LocalBuffer struct {
+	l    *Logger // 创建缓冲的日志
+	root *Logger
+
+	local    bool // 创建时是否已开启本地缓冲
+	size     int
+	interval time.Duration
+	enc      Encoder
+	fields   []Field
+	name     string
+	sep      Separator
+	clock    Clock
+
+	mu      sync.Mutex // 交接与写入的互斥锁，只在交接时有竞争
+	buf     *Buffer
+	entries int
+	levels  [FATAL + 1]int64 // 缓冲中各级别的条数
+	last    time.Time        // 上次交接的时间
+}
+
+// 创建本地缓冲，派生日志的字段与来源随之输出
+func (l *Logger) NewLocalBuffer() *LocalBuffer {
+	b := &LocalBuffer{l: l}
+	if l.noop() {
+		return b
+	}
+
+	root := l
+	if l.root != nil {
+		root = l.root
+	}
+	b.root = root
+
+	root.locals.mu.Lock()
+	b.local, b.size, b.interval = root.locals.size > 0, root.locals.size, root.locals.interval
+	root.locals.mu.Unlock()
+	if !b.local {
+		return b
+	}
+
+	l.mu.Lock()
+	b.fields = l.mergeFields(nil)
+	b.name = l.name
+	l.mu.Unlock()
+
+	root.mu.Lock()
+	b.enc = root.colorEncoder(root.encoder, root.colorOff())
+	b.sep = root.separator
+	b.clock = root.clock
+	root.mu.Unlock()
+	b.buf = &Buffer{}
+	b.last = b.clock.Now()
+
+	root.locals.mu.Lock()
+	if root.locals.buffers == nil {
+		root.locals.buffers = map[*LocalBuffer]struct{}{}
+	}
+	root.locals.buffers[b] = struct{}{}
+	root.locals.mu.Unlock()
+	return b
+}
+
+// 输出一条日志，kvs 为交替的键值对
+func (b *LocalBuffer) Log(level LogType, msg string, kvs ...interface{}) LogResult {
+	if !b.local {
+		return b.l.log(level, msg, kvsToFields(kvs)...)
+	}
+	return b.append(level, msg, kvs)
+}
+
+func (b *LocalBuffer) Debugw(msg string, kvs ...interface{}) LogResult {
+	if !b.local {
+		return b.l.log(DEBUG, msg, kvsToFields(kvs)...)
+	}
+	return b.append(DEBUG, msg, kvs)
+}
+
+func (b *LocalBuffer) Infow(msg string, kvs ...interface{}) LogResult {
+	if !b.local {
+		return b.l.log(INFO, msg, kvsToFields(kvs)...)
+	}
+	return b.append(INFO, msg, kvs)
+}
+
+func (b *LocalBuffer) Warnw(msg string, kvs ...interface{}) LogResult {
+	if !b.local {
+		return b.l.log(WARN, msg, kvsToFields(kvs)...)
+	}
+	return b.append(WARN, msg, kvs)
+}
+
+func (b *LocalBuffer) Errorw(msg string, kvs ...interface{}) LogResult {
+	if !b.local {
+		return b.l.log(ERROR, msg, kvsToFields(kvs)...)
+	}
+	return b.append(ERROR, msg, kvs)
+}
+
+// 编码到本地缓冲，达到阈值时交接
+func (b *LocalBuffer) append(level LogType, msg string, kvs []interface{}) LogResult {
+	if b.l.logLevel.Level() > level {
+		return LogFiltered
+	}
+
+	e := getEntry()
+	e.Level, e.Time, e.Message = level, b.clock.Now(), msg
+	e.Fields = append(b.fields[:len(b.fields):len(b.fields)], kvsToFields(kvs)...)
+	tmp := GetBuffer()
+	defer tmp.Free()
+	err := encodeTo(b.enc, tmp, e)
+	putEntry(e)
+	if err != nil {
+		b.root.handleError(err)
+		return LogInvalid
+	}
+	b.sep.apply(tmp)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Write(tmp.Bytes())
+	b.entries++
+	if int(level) < len(b.levels) {
+		b.levels[level]++
+	}
+	if b.buf.Len() >= b.size || b.clock.Now().Sub(b.last) >= b.interval {
+		return b.handoff()
+	}
+	return LogAccepted
+}
+
+// 将缓冲中的日志交给主输出
+func (b *LocalBuffer) Flush() {
+	if !b.local {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handoff()
+}
+
+// 交接剩余的日志并注销缓冲，之后不能再使用
+func (b *LocalBuffer) Close() {
+	if !b.local {
+		return
+	}
+	b.Flush()
+	b.root.locals.mu.Lock()
+	delete(b.root.locals.buffers, b)
+	b.root.locals.mu.Unlock()
+}
+
+// 整批交给根日志的缓存或队列，调用方需持有 b.mu
+func (b *LocalBuffer) handoff() LogResult {
+	b.last = b.clock.Now()
+	if b.entries == 0 {
+		return LogAccepted
+	}
+	entries, levels := b.entries, b.levels
+	b.entries, b.levels = 0, [FATAL + 1]int64{}
+
+	root := b.root
+	if root.stats != nil {
+		root.stats.addBatch(b.name, levels, b.buf.Len())
+	}
+	buf := GetBuffer()
+	buf.Write(b.buf.Bytes())
+	b.buf.Reset()
+	return root.outputBatch(buf, entries)
+}
+
+// 交接距上次交接超过间隔的本地缓冲，force 为 true 时交接全部
+func (l *Logger) handoffLocals(force bool) {
+	l.locals.mu.Lock()
+	buffers := make([]*LocalBuffer, 0, len(l.locals.buffers))
+	for b := range l.locals.buffers {
+		buffers = append(buffers, b)
+	}
+	l.locals.mu.Unlock()
+
+	for _, b := range buffers {
+		b.mu.Lock()
+		if force || b.clock.Now().Sub(b.last) >= b.interval {
+			b.handoff()
+		}
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+/*
+ * 创建日志管理器
+ *
+ * 管理器持有一个根日志，按名称派生的组件日志共用根日志的定时刷新goroutine、缓存、队列与输出，
+ * 应用中几十个组件日志也只有一套后台资源。root 为 nil 时使用 NewLogger() 创建。
+ *
+ *   m := logger.NewManager(nil)
+ *   m.Start()
+ *   db := m.Logger("db")
+ *   db.SetLogLevel(logger.WARN)
+ */
+func NewManager(root *Logger) *Manager {
+	if root == nil {
+		root = NewLogger()
+	}
+	return &Manager{root: root, loggers: map[string]*Logger{}}
+}
+
+// 日志管理器
+type Manager struct {
+	mu      sync.Mutex
+	root    *Logger            // 根日志，持有共享的输出通路
+	loggers map[string]*Logger // 按名称派生的组件日志
+}
+
+// 启动共享的输出通路，ctx 取消时关闭，见 Logger.Start
+func (m *Manager) Start(ctx ...context.Context) {
+	m.root.Start(ctx...)
+}
+
+// 获取根日志，输出相关设置在根日志上进行
+func (m *Manager) Root() *Logger {
+	return m.root
+}
+
+// 获取指定名称的组件日志，不存在时创建
+// 组件日志携带 logger 字段，输出统计按名称归属
+func (m *Manager) Logger(name string) *Logger {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if l, ok := m.loggers[name]; ok {
+		return l
+	}
+	l := m.root.With("logger", name)
+	l.SetName(name)
+	m.loggers[name] = l
+	return l
+}
+
+// 已创建的组件日志名称
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.loggers))
+	for name := range m.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 设置所有组件日志(含根日志)的级别
+func (m *Manager) SetLogLevel(logType LogType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.root.SetLogLevel(logType)
+	for _, l := range m.loggers {
+		l.SetLogLevel(logType)
+	}
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// 降级模式至少保持的时长，期间未再超限才恢复异步写出
+const memoryRecoverDelay = time.Second
+
+// 超过内存上限转为同步写出，可通过 errors.Is 判断
+var ErrMemoryLimit = errors.New("logger: memory limit reached")
+
+// 内存上限控制
+type memoryLimit struct {
+	limit      int64     // 上限(字节)，0 表示不限制，由 l.mu 保护
+	queued     int64     // 队列中日志的字节数
+	degraded   bool      // 是否处于同步降级模式，由 l.mu 保护
+	exceeded   time.Time // 最近一次超限的时间，由 l.mu 保护
+	syncWrites int64     // 降级模式下同步写出的条数
+}
+
+/*
+ * 设置日志占用内存的上限(字节)，0 表示不限制
+ *
+ *   l.SetMemoryLimit(64 << 20)
+ *
+ * 统计缓存、队列、附加输出端的待写日志与积压占用的字节数。日志产生快于写出、将要超过上限时
+ * 转为同步降级模式：先写出已缓存的日志，之后每条日志在调用中直接写出，附加输出端同样同步写出，
+ * 其积压未能写出时丢弃新日志，内存不再增长。进入降级模式时以 ErrMemoryLimit 交给错误处理函数，
+ * 持续 1 秒未再超限后恢复异步写出。降级期间同步写出的条数见 Stats().SyncWrites。
+ * 缓冲池只保留 64KB 以下的缓冲，不计入上限。派生日志设置根日志的上限。
+ */
+func (l *Logger) SetMemoryLimit(n int64) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetMemoryLimit(n)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mem.limit = n
+	if n <= 0 {
+		l.mem.degraded = false
+	}
+}
+
+// 缓存、队列与附加输出端占用的字节数，不需要持有 l.mu
+func (l *Logger) memoryUsage() int64 {
+	l.cache.mutex.Lock()
+	n := int64(l.cache.buf.Len())
+	l.cache.mutex.Unlock()
+	n += atomic.LoadInt64(&l.mem.queued)
+	for _, s := range l.sinkView() {
+		n += atomic.LoadInt64(&s.pendingBytes) + atomic.LoadInt64(&s.backlogBytes)
+	}
+	return n
+}
+
+// 按内存上限更新降级状态，返回是否同步写出 n 字节的日志。调用方需持有 l.mu
+func (l *Logger) checkMemory(n int) bool {
+	if l.mem.limit <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	if usage := l.memoryUsage() + int64(n); usage > l.mem.limit {
+		l.mem.exceeded = now
+		if !l.mem.degraded {
+			l.mem.degraded = true
+			// 持有 l.mu，解锁后上报
+			l.deferError(fmt.Errorf("%w: %d bytes buffered, limit %d, writing synchronously",
+				ErrMemoryLimit, usage, l.mem.limit))
+		}
+	} else if l.mem.degraded && now.Sub(l.mem.exceeded) >= memoryRecoverDelay {
+		l.mem.degraded = false
+	}
+	return l.mem.degraded
+}
+
+// 降级模式下同步写出：先排空缓存和队列保证顺序，再直接写出本条。调用方需持有 l.mu
+func (l *Logger) writeSync(buf *Buffer) LogResult {
+	defer buf.Free()
+	atomic.AddInt64(&l.mem.syncWrites, 1)
+
+	err := l.Drain()
+	if err == nil {
+		l.drainMu.Lock()
+		err = l.write(buf.Bytes())
+		l.drainMu.Unlock()
+	}
+	if err != nil {
+		l.deferError(err)
+		atomic.AddInt64(&l.dropped, 1)
+		return LogDropped
+	}
+	return LogAccepted
+}
+
+// 降级模式下同步写出，待写日志或积压未能写出时丢弃新日志，避免积压继续增长
+func (s *Sink) writeSync(q queued) error {
+	if err := s.flush(); err != nil {
+		atomic.AddInt64(&s.dropped, 1)
+		q.buf.Free()
+		return err
+	}
+	s.enqueue(q)
+	return s.flush()
+}
+
+// 队列中日志的字节数
+func queuedBytes(items []queued) int64 {
+	n := 0
+	for _, q := range items {
+		if q.buf != nil {
+			n += q.buf.Len()
+		}
+	}
+	return int64(n)
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// 日志调用耗时直方图的桶上界
+var histogramBounds = [...]time.Duration{
+	250 * time.Nanosecond, 500 * time.Nanosecond,
+	time.Microsecond, 2500 * time.Nanosecond, 5 * time.Microsecond,
+	10 * time.Microsecond, 25 * time.Microsecond, 50 * time.Microsecond,
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond,
+}
+
+// 耗时直方图快照
+type Histogram struct {
+	Bounds []time.Duration // 各桶上界，升序
+	Counts []int64         // 各桶计数(非累计)，比 Bounds 多一个超出最大上界的桶
+	Count  int64           // 总次数
+	Sum    time.Duration   // 总耗时
+}
+
+// 平均耗时
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// 分位数的近似值(所在桶的上界)，超出最大上界时返回最大上界
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 || len(h.Bounds) == 0 {
+		return 0
+	}
+	rank := int64(q * float64(h.Count))
+	var seen int64
+	for i, c := range h.Counts {
+		seen += c
+		if seen > rank && i < len(h.Bounds) {
+			return h.Bounds[i]
+		}
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// 耗时直方图计数器
+type histogram struct {
+	enabled int32
+	counts  [len(histogramBounds) + 1]int64
+	sum     int64 // 纳秒
+}
+
+// 记录一次从 start 开始的耗时
+func (h *histogram) observe(start time.Time) {
+	d := time.Since(start)
+	i := sort.Search(len(histogramBounds), func(i int) bool { return d <= histogramBounds[i] })
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+// 生成快照，未开启时返回零值
+func (h *histogram) snapshot() Histogram {
+	if atomic.LoadInt32(&h.enabled) == 0 {
+		return Histogram{}
+	}
+	s := Histogram{
+		Bounds: append([]time.Duration(nil), histogramBounds[:]...),
+		Counts: make([]int64, len(h.counts)),
+		Sum:    time.Duration(atomic.LoadInt64(&h.sum)),
+	}
+	for i := range h.counts {
+		s.Counts[i] = atomic.LoadInt64(&h.counts[i])
+		s.Count += s.Counts[i]
+	}
+	return s
+}
+
+// 设置是否统计日志调用耗时(调用方在日志方法中花费的时间)，结果见 Stats().CallLatency 与 MetricsHandler
+// 派生日志的调用计入根日志
+func (l *Logger) SetCallLatency(enable bool) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetCallLatency(enable)
+		return
+	}
+	v := int32(0)
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&l.callLatency.enabled, v)
+}
+
+// 返回 Prometheus 文本格式的指标
+func (l *Logger) MetricsHandler() http.Handler {
+	if l.noop() {
+		return http.NotFoundHandler()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		l.WritePrometheus(w)
+	})
+}
+
+// 以 Prometheus 文本格式写出输出统计与调用耗时直方图
+func (l *Logger) WritePrometheus(w io.Writer) error {
+	if l.noop() {
+		return nil
+	}
+
+	st := l.Stats()
+	counters := []struct {
+		name, help string
+		value      int64
+	}{
+		{"logger_entries_total", "Entries written to the primary output.", st.Entries},
+		{"logger_bytes_total", "Bytes written to the primary output.", st.Bytes},
+		{"logger_dropped_total", "Entries dropped by the queue overflow policy.", st.Dropped},
+		{"logger_expired_total", "Entries dropped after their TTL.", st.Expired},
+		{"logger_latency_violations_total", "Batches written later than the latency contract.", st.LatencyViolations},
+	}
+	for _, c := range counters {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.value); err != nil {
+			return err
+		}
+	}
+
+	h := st.CallLatency
+	if h.Count == 0 {
+		return nil
+	}
+	const name = "logger_call_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time spent inside log calls.\n# TYPE %s histogram\n", name, name)
+	var cumulative int64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, bound.Seconds(), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, h.Sum.Seconds())
+	_, err := fmt.Fprintf(w, "%s_count %d\n", name, h.Count)
+	return err
+}
+
+// 根日志的耗时直方图
+func (l *Logger) callHistogram() *histogram {
+	if l.root != nil {
+		return &l.root.callLatency
+	}
+	return &l.callLatency
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	netDialTimeout  = 5 * time.Second        // 建立连接的超时
+	netWriteTimeout = 5 * time.Second        // 单次写出的超时
+	minNetBackoff   = 100 * time.Millisecond // 首次重连的等待时间
+	maxNetBackoff   = 30 * time.Second       // 重连等待时间上限
+)
+
+var (
+	// 连接断开后等待重连期间的写出错误，输出端保留日志稍后重试，不上报
+	errNetBackoff = errors.New("logger: waiting to reconnect")
+	errNetClosed  = errors.New("logger: network output closed")
+)
+
+// 网络连接，断开后按指数退避重连。方法调用方需持有 mu
+type netConn struct {
+	mu       sync.Mutex
+	name     string                   // 用于错误信息的地址描述
+	dial     func() (net.Conn, error) // 建立连接
+	datagram bool                     // 数据报协议，每条日志单独发送，连接时按地址设置
+	conn     net.Conn
+	backoff  time.Duration // 当前的重连等待时间
+	retryAt  time.Time     // 下次允许重连的时间
+	closed   bool
+}
+
+// 按行写出，未连接时先连接，写出失败时断开等待重连
+func (c *netConn) write(p []byte) (int, error) {
+	if c.closed {
+		return 0, errNetClosed
+	}
+	if c.conn == nil {
+		if time.Now().Before(c.retryAt) {
+			return 0, errNetBackoff
+		}
+		conn, err := c.dial()
+		if err != nil {
+			c.fail()
+			return 0, fmt.Errorf("logger: dial %s: %w", c.name, err)
+		}
+		c.conn = conn
+		if addr := conn.RemoteAddr(); addr != nil {
+			c.datagram = isDatagram(addr.Network())
+		}
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(netWriteTimeout))
+	n, err := c.send(p)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.fail()
+		return n, fmt.Errorf("logger: write %s: %w", c.name, err)
+	}
+	c.backoff = 0
+	return n, nil
+}
+
+// 发送数据，数据报协议按行拆分且不带换行
+func (c *netConn) send(p []byte) (int, error) {
+	if !c.datagram {
+		return c.conn.Write(p)
+	}
+	n := 0
+	for len(p) > 0 {
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i]
+			p = p[i+1:]
+		} else {
+			p = nil
+		}
+		if len(line) > 0 {
+			if _, err := c.conn.Write(line); err != nil {
+				return n, err
+			}
+		}
+		n += len(line) + 1
+	}
+	return n, nil
+}
+
+// 记录一次失败，加倍重连等待时间
+func (c *netConn) fail() {
+	if c.backoff == 0 {
+		c.backoff = minNetBackoff
+	} else if c.backoff *= 2; c.backoff > maxNetBackoff {
+		c.backoff = maxNetBackoff
+	}
+	c.retryAt = time.Now().Add(c.backoff)
+}
+
+// 关闭连接，之后的写出均返回错误
+func (c *netConn) close() error {
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+/*
+ * 创建 TCP/UDP 输出，每条日志编码为一行 JSON 发送
+ *
+ *   w := logger.NewNetSink("tcp", "collector:5170")
+ *   l.AddOutput(w, logger.WARN)
+ *   defer w.Close()
+ *
+ * 连接在首次写出时建立，断开后按指数退避(100ms 至 30s)重连；其间日志留在输出端的积压中，
+ * 重连后按顺序补写，见 Sink。UDP 每条日志单独发送一个数据报。
+ */
+func NewNetSink(network, addr string) *NetSink {
+	s := &NetSink{}
+	s.conn.name = network + "://" + addr
+	s.conn.dial = func() (net.Conn, error) {
+		return net.DialTimeout(network, addr, netDialTimeout)
+	}
+	return s
+}
+
+// TCP/UDP 输出，同时是 JSON 编码器，AddOutput 时作为输出端的编码器
+type NetSink struct {
+	JSONEncoder
+	conn netConn
+}
+
+// 声明接口实现者
+var _ BufferEncoder = &NetSink{}
+
+func (s *NetSink) Write(p []byte) (int, error) {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	return s.conn.write(p)
+}
+
+func (s *NetSink) Close() error {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	return s.conn.close()
+}
+
+// 是否为数据报协议
+func isDatagram(network string) bool {
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// 队列满时的处理策略
+type OverflowPolicy int
+
+const (
+	OverflowBlock      = OverflowPolicy(0) // 阻塞日志调用直到队列有空位(默认)，不丢日志
+	OverflowDropNewest = OverflowPolicy(1) // 丢弃新日志，日志调用不阻塞
+	OverflowDropOldest = OverflowPolicy(2) // 丢弃队列中最旧的日志，保留最新的日志
+)
+
+/*
+ * 设置队列模式(SetCacheSwitch(false))下队列满时的处理策略
+ *
+ *   l.SetOverflowPolicy(logger.OverflowDropNewest)
+ *   l.SetDropHandler(func(msg string) { metrics.Inc("log_dropped") })
+ *
+ * 丢弃的条数可通过 Stats().Dropped 查看。
+ */
+func (l *Logger) SetOverflowPolicy(policy OverflowPolicy) {
+	if l.noop() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.overflow = policy
+}
+
+// 设置日志因队列满被丢弃时的回调，参数为被丢弃的日志内容
+// 回调在日志调用中同步执行，应尽快返回且不能再输出日志
+func (l *Logger) SetDropHandler(handler func(msg string)) {
+	if l.noop() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onDrop = handler
+}
+
+// 按队列满时的处理策略入队，新日志被丢弃时返回 false。调用方需持有 l.mu
+func (l *Logger) enqueue(q queued) bool {
+	switch l.overflow {
+	case OverflowDropNewest:
+		select {
+		case l.queue <- q:
+		default:
+			l.drop(q)
+			return false
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case l.queue <- q:
+				return true
+			default:
+			}
+			// 队列满，丢弃最旧的一条日志后重试；队列中只有业务事件时等待入队
+			if !l.dropOldest() {
+				l.queue <- q
+				return true
+			}
+		}
+	default:
+		l.queue <- q
+	}
+	return true
+}
+
+// 丢弃队列中最旧的一条非业务事件日志，其他日志保持原顺序，没有可丢弃的日志时返回 false。调用方需持有 l.mu
+func (l *Logger) dropOldest() bool {
+	var kept []queued
+	dropped := false
+drain:
+	for n := len(l.queue); n > 0; n-- {
+		select {
+		case old := <-l.queue:
+			if !dropped && !old.event {
+				l.drop(old)
+				dropped = true
+				if len(kept) == 0 {
+					// 最旧的一条即被丢弃，其余不必取出
+					return true
+				}
+				continue
+			}
+			kept = append(kept, old)
+		default:
+			break drain
+		}
+	}
+
+	// 取出的日志按原顺序放回，入队都持有 l.mu，放回不会阻塞
+	for _, old := range kept {
+		l.queue <- old
+	}
+	return dropped
+}
+
+// 记录一条被丢弃的日志
+func (l *Logger) drop(q queued) {
+	atomic.AddInt64(&l.dropped, 1)
+	atomic.AddInt64(&l.mem.queued, -int64(q.buf.Len()))
+	if l.onDrop != nil {
+		l.onDrop(q.buf.String())
+	}
+	q.buf.Free()
+}
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	}
+	return "unknown"
+}
+
+// 由名称解析处理策略，空串为 OverflowBlock
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	if s == "" {
+		return OverflowBlock, nil
+	}
+	for _, p := range []OverflowPolicy{OverflowBlock, OverflowDropNewest, OverflowDropOldest} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return OverflowBlock, fmt.Errorf("logger: unknown overflow policy %q", s)
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// 可在启动前检查的输出，自定义的输出端(如需鉴权的 HTTP 接口)实现后参与 Preflight
+type Preflighter interface {
+	Preflight() error
+}
+
+// 声明接口实现者
+var (
+	_ Preflighter = &NetSink{}
+	_ Preflighter = &SyslogSink{}
+)
+
+/*
+ * 检查主输出、备用输出与各输出端是否可用，用于服务就绪前
+ *
+ *   if err := l.Preflight(); err != nil {
+ *       log.Fatalf("logging not ready: %v", err) // 部署失败，而不是等到下次故障才发现日志没有写出
+ *   }
+ *
+ * 文件检查可写入(回滚文件还检查目录可创建文件)，NetSink、SyslogSink 建立连接并保留供之后写出，
+ * 实现 Preflighter 的输出调用其 Preflight，其他输出视为可用。各输出的错误全部返回，可用 errors.Is 判断。
+ * 派生日志检查根日志
+ */
+func (l *Logger) Preflight() error {
+	if l.noop() {
+		return nil
+	}
+
+	if l.root != nil {
+		return l.root.Preflight()
+	}
+
+	// 快照后在锁外检查，回滚文件的检查需要其输出锁
+	l.outMu.Lock()
+	writers := []io.Writer{l.out}
+	if l.fallback != nil && l.fallback != l.out {
+		writers = append(writers, l.fallback)
+	}
+	l.outMu.Unlock()
+	l.mu.Lock()
+	for _, s := range l.sinks {
+		writers = append(writers, s.Writer)
+	}
+	l.mu.Unlock()
+
+	var errs []error
+	for _, w := range writers {
+		if err := preflight(w); err != nil {
+			errs = append(errs, fmt.Errorf("logger: preflight %s: %w", writerName(w), err))
+		}
+	}
+	return joinErrors(errs...)
+}
+
+// 检查一个输出
+func preflight(w io.Writer) error {
+	switch w := w.(type) {
+	case *RotateFileLogger:
+		// Preflight 为组合的日志的方法，检查其全部输出
+		return w.checkFile()
+	case *SharedFile:
+		return w.f.checkFile()
+	case Preflighter:
+		return w.Preflight()
+	case *os.File:
+		// 写入 0 字节可发现已关闭或只读的文件
+		_, err := w.Write(nil)
+		return err
+	}
+	return nil
+}
+
+// 建立连接并保留，之后的写出直接使用
+func (s *NetSink) Preflight() error {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	return s.conn.preflight()
+}
+
+// 建立连接并保留，之后的写出直接使用
+func (s *SyslogSink) Preflight() error {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	return s.conn.preflight()
+}
+
+// 未连接时不论是否在重连等待中都立即连接
+func (c *netConn) preflight() error {
+	if c.closed {
+		return errNetClosed
+	}
+	if c.conn != nil {
+		return nil
+	}
+	conn, err := c.dial()
+	if err != nil {
+		c.fail()
+		return fmt.Errorf("logger: dial %s: %w", c.name, err)
+	}
+	c.conn, c.backoff, c.retryAt = conn, 0, time.Time{}
+	if addr := conn.RemoteAddr(); addr != nil {
+		c.datagram = isDatagram(addr.Network())
+	}
+	return nil
+}
+
+// 检查当前文件可写入、日志目录可创建文件(切换时需要)
+func (l *RotateFileLogger) checkFile() error {
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("%w: %s", ErrOutputClosed, l.filePath)
+	}
+	if _, err := l.file.Write(nil); err != nil {
+		return err
+	}
+	dir := l.dirPath
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,58 @@
+package logger
+
+// 延迟格式化的参数，通过级别过滤后才调用 fmt.Sprint / fmt.Sprintf，被过滤的日志不产生格式化开销
+type (
+	sprintArgs  []interface{}
+	sprintfArgs struct {
+		format string
+		args   []interface{}
+	}
+)
+
+// 将日志方法的参数转为日志内容，单个参数见 messageArg
+// 多个参数时复制一份，使调用方的可变参数切片不逃逸到堆上
+func message(args []interface{}) interface{} {
+	if len(args) == 1 {
+		return messageArg(args[0])
+	}
+	return sprintArgs(append([]interface{}(nil), args...))
+}
+
+// 单个参数的日志内容：字符串、数据列、访问与变更记录原样使用，
+// 其他类型(error、数字等)按 fmt.Sprint 格式化，级别被过滤时不格式化
+func messageArg(arg interface{}) interface{} {
+	switch arg.(type) {
+	case string, []string, *AccessEntry, *DiffEntry:
+		return arg
+	}
+	return sprintArgs{arg}
+}
+
+// 按 fmt.Sprintf 格式输出，级别被过滤时不格式化
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(DEBUG, sprintfArgs{format: format, args: args})
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(INFO, sprintfArgs{format: format, args: args})
+}
+
+func (l *Logger) Noticef(format string, args ...interface{}) {
+	l.log(NOTICE, sprintfArgs{format: format, args: args})
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(WARN, sprintfArgs{format: format, args: args})
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(ERROR, sprintfArgs{format: format, args: args})
+}
+
+func (l *Logger) Criticalf(format string, args ...interface{}) {
+	l.log(CRITICAL, sprintfArgs{format: format, args: args})
+}
+
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(FATAL, sprintfArgs{format: format, args: args})
+}
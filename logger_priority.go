@@ -0,0 +1,70 @@
+package logger
+
+// 采样优先级字段的键，Datadog 等后端按此决定是否参与采样
+const PriorityKey = "sampling.priority"
+
+// 常用的采样优先级
+const (
+	PriorityDrop = 0 // 可被后端采样丢弃
+	PriorityAuto = 1 // 按后端的采样规则
+	PriorityKeep = 2 // 始终保留，不参与后端采样
+)
+
+// 单条日志的采样优先级字段，优先于按级别设置的优先级
+//
+//	l.Infow("payment settled", logger.Priority(logger.PriorityKeep))
+func Priority(p int) Field {
+	return Field{Key: PriorityKey, Value: p}
+}
+
+// 各级别的采样优先级
+type levelPriorities struct {
+	values [FATAL + 1]int
+	set    [FATAL + 1]bool
+}
+
+/*
+ * 按级别附加采样优先级字段 sampling.priority，为 nil 时关闭
+ *
+ *   l.SetLevelPriorities(map[logger.LogType]int{logger.ERROR: logger.PriorityKeep, logger.FATAL: logger.PriorityKeep})
+ *
+ * 后端采样较激进时，高价值的日志可据此保留。未列出的级别不附加字段，已带 sampling.priority 字段的日志
+ * (如使用 Priority)保持不变。派生日志设置根日志的优先级。
+ */
+func (l *Logger) SetLevelPriorities(priorities map[LogType]int) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetLevelPriorities(priorities)
+		return
+	}
+
+	var lp *levelPriorities
+	if priorities != nil {
+		lp = &levelPriorities{}
+		for level, p := range priorities {
+			if int(level) < len(lp.values) {
+				lp.values[level], lp.set[level] = p, true
+			}
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.priorities = lp
+}
+
+// 附加级别对应的优先级字段
+func (lp *levelPriorities) apply(level LogType, fields []Field) []Field {
+	if int(level) >= len(lp.values) || !lp.set[level] {
+		return fields
+	}
+	for _, f := range fields {
+		if f.Key == PriorityKey {
+			return fields
+		}
+	}
+	return append(fields[:len(fields):len(fields)], Priority(lp.values[level]))
+}
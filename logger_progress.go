@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 进度日志默认的最小输出间隔
+const DefaultProgressInterval = 5 * time.Second
+
+/*
+ * 长时间操作的进度
+ *
+ *   p := l.Progress("import", int64(len(rows)))
+ *   for _, row := range rows {
+ *       importRow(row)
+ *       p.Add(1)
+ *   }
+ *   p.Done()
+ *
+ * Add 累加完成数，距上次输出超过间隔(默认 5 秒，见 SetInterval)时以 INFO 级别输出 "import progress"，
+ * 字段为 done、total、percent(百分比)、rate(每秒完成数)、eta_ms(预计剩余毫秒)与 elapsed_ms；
+ * 总数未知(total <= 0)时不输出 total、percent 与 eta_ms。完成数达到总数或调用 Done 时输出一次 "import done"。
+ * 可并发调用。时间取自日志的时间来源，见 SetClock。
+ */
+type Progress struct {
+	l        *Logger
+	name     string
+	total    int64
+	clock    Clock
+	mu       sync.Mutex
+	interval time.Duration
+	done     int64
+	start    time.Time
+	last     time.Time // 最近一次输出的时间
+	finished bool
+}
+
+// 创建进度，total 为总数，未知时为 0
+func (l *Logger) Progress(name string, total int64) *Progress {
+	p := &Progress{l: l, name: name, total: total, interval: DefaultProgressInterval, clock: systemClock{}}
+	if !l.noop() {
+		root := l
+		if l.root != nil {
+			root = l.root
+		}
+		root.mu.Lock()
+		p.clock = root.clock
+		root.mu.Unlock()
+	}
+	p.start = p.clock.Now()
+	p.last = p.start
+	return p
+}
+
+// 设置最小输出间隔
+func (p *Progress) SetInterval(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interval = d
+}
+
+// 累加完成数，按间隔输出进度，达到总数时输出完成
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	p.done += n
+	now := p.clock.Now()
+	if p.finished {
+		p.mu.Unlock()
+		return
+	}
+	if p.total > 0 && p.done >= p.total {
+		p.mu.Unlock()
+		p.finish(callerPC(1))
+		return
+	}
+	if now.Sub(p.last) < p.interval {
+		p.mu.Unlock()
+		return
+	}
+	p.last = now
+	fields := p.fields(now)
+	p.mu.Unlock()
+
+	p.l.logAt(callerPC(1), INFO, p.name+" progress", fields)
+}
+
+// 结束并输出完成，只输出一次
+func (p *Progress) Done() {
+	p.finish(callerPC(1))
+}
+
+// 输出完成
+func (p *Progress) finish(pc uintptr) {
+	p.mu.Lock()
+	if p.finished {
+		p.mu.Unlock()
+		return
+	}
+	p.finished = true
+	now := p.clock.Now()
+	elapsed := now.Sub(p.start)
+	fields := []Field{{Key: "done", Value: p.done}}
+	if p.total > 0 {
+		fields = append(fields, Field{Key: "total", Value: p.total})
+	}
+	fields = append(fields,
+		Field{Key: "rate", Value: progressRate(p.done, elapsed)},
+		Field{Key: "elapsed_ms", Value: elapsed.Milliseconds()})
+	p.mu.Unlock()
+
+	p.l.logAt(pc, INFO, p.name+" done", fields)
+}
+
+// 进度字段，调用方需持有 p.mu
+func (p *Progress) fields(now time.Time) []Field {
+	elapsed := now.Sub(p.start)
+	rate := progressRate(p.done, elapsed)
+	fields := []Field{{Key: "done", Value: p.done}}
+	if p.total > 0 {
+		fields = append(fields,
+			Field{Key: "total", Value: p.total},
+			Field{Key: "percent", Value: math.Round(float64(p.done)*1000/float64(p.total)) / 10})
+	}
+	fields = append(fields, Field{Key: "rate", Value: rate})
+	if p.total > 0 && rate > 0 {
+		eta := time.Duration(float64(p.total-p.done) / rate * float64(time.Second))
+		fields = append(fields, Field{Key: "eta_ms", Value: eta.Milliseconds()})
+	}
+	return append(fields, Field{Key: "elapsed_ms", Value: elapsed.Milliseconds()})
+}
+
+// 每秒完成数，保留一位小数
+func progressRate(done int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return math.Round(float64(done)/elapsed.Seconds()*10) / 10
+}
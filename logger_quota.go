@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// 输出配额
+type Quota struct {
+	Window     time.Duration // 统计窗口，如 time.Hour
+	MaxBytes   int64         // 窗口内最多写出的字节数，0 表示不限
+	MaxEntries int64         // 窗口内最多写出的条数，0 表示不限
+	Divert     io.Writer     // 超额后转写的输出(如本地文件)，为 nil 时直接丢弃
+}
+
+/*
+ * 创建带配额的输出
+ *
+ * 按行计算条数与字节数，窗口内超额后剩余日志转写到 Divert 或丢弃，
+ * 并向原输出和 Divert 各写一条超额标记，窗口结束后自动恢复。
+ *
+ *   l.SetOutput(logger.NewQuotaWriter(conn, logger.Quota{Window: time.Hour, MaxBytes: 50 << 20}))
+ */
+func NewQuotaWriter(w io.Writer, q Quota) *QuotaWriter {
+	if q.Window <= 0 {
+		panic("logger: quota window must be positive")
+	}
+	return &QuotaWriter{out: w, quota: q, start: time.Now()}
+}
+
+// 带配额的输出
+type QuotaWriter struct {
+	mu       sync.Mutex
+	out      io.Writer // 原输出
+	quota    Quota     // 配额
+	start    time.Time // 当前窗口起始时间
+	bytes    int64     // 当前窗口已写字节数
+	entries  int64     // 当前窗口已写条数
+	exceeded bool      // 当前窗口是否已超额
+	dropped  int64     // 累计超额条数
+}
+
+func (w *QuotaWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// 进入新窗口时复位计数
+	now := time.Now()
+	if now.Sub(w.start) >= w.quota.Window {
+		w.start = now
+		w.bytes = 0
+		w.entries = 0
+		w.exceeded = false
+	}
+
+	// 按行拆分，逐条计算配额
+	allowed := p
+	var excess []byte
+	rest := p
+	for len(rest) > 0 {
+		n := bytes.IndexByte(rest, '\n') + 1
+		if n == 0 {
+			n = len(rest)
+		}
+		if (w.quota.MaxBytes > 0 && w.bytes+int64(n) > w.quota.MaxBytes) ||
+			(w.quota.MaxEntries > 0 && w.entries+1 > w.quota.MaxEntries) {
+			allowed = p[:len(p)-len(rest)]
+			excess = rest
+			break
+		}
+		w.bytes += int64(n)
+		w.entries++
+		rest = rest[n:]
+	}
+
+	if len(allowed) > 0 {
+		if _, err := w.out.Write(allowed); err != nil {
+			return 0, err
+		}
+	}
+	if len(excess) == 0 {
+		return len(p), nil
+	}
+
+	w.dropped += int64(bytes.Count(excess, []byte{'\n'}))
+	if !w.exceeded {
+		// 每个窗口只写一次超额标记
+		w.exceeded = true
+		marker := w.marker(now)
+		io.WriteString(w.out, marker)
+		if w.quota.Divert != nil {
+			io.WriteString(w.quota.Divert, marker)
+		}
+	}
+	if w.quota.Divert != nil {
+		if _, err := w.quota.Divert.Write(excess); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// 累计超额(被转写或丢弃)的条数
+func (w *QuotaWriter) Dropped() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// 超额标记，沿用文本日志格式便于解析
+func (w *QuotaWriter) marker(now time.Time) string {
+	action := "dropped"
+	if w.quota.Divert != nil {
+		action = "diverted"
+	}
+	return fmt.Sprintf("[ %s ] %s | quota exceeded | %d bytes / %d entries in %s | remaining entries %s until %s | \n",
+		logTypeStrings[WARN], now.Format(recordTimeLayout), w.bytes, w.entries, w.quota.Window,
+		action, w.start.Add(w.quota.Window).Format(recordTimeLayout))
+}
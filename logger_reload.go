@@ -0,0 +1,289 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 由配置构建的输出通路
+type pipeline struct {
+	level    LogType
+	out      io.Writer
+	encoder  Encoder
+	sinks    []*Sink
+	closers  []io.Closer // 通路创建的文件，替换或关闭日志时关闭
+	cache    bool
+	overflow OverflowPolicy
+	color    ColorMode
+	tenants  *tenantState // 租户策略，nil 表示不按租户区分
+	sep      Separator
+}
+
+/*
+ * 按配置替换整个输出通路(级别、主输出、编码器、附加输出端及其字段过滤、租户策略)
+ *
+ *   if err := l.ApplyConfig(cfg); err != nil { ... } // 配置有误时保持原通路不变
+ *
+ * 替换期间日志调用短暂等待：先将旧通路中已生成的日志按旧编码写入旧输出，再切换到新通路，
+ * 旧的附加输出端写完各自的缓冲后关闭。切换前后的日志不丢失也不重复。
+ * cache_duration 与 queue_size 只在 Start 前生效；级别只作用于根日志，派生日志保留各自的级别，未设置级别时保持原级别。
+ */
+func (l *Logger) ApplyConfig(cfg Config) error {
+	if l.noop() {
+		return nil
+	}
+
+	if l.root != nil {
+		return l.root.ApplyConfig(cfg)
+	}
+	if errs := ValidateConfig(cfg); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	p, err := buildPipeline(cfg)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	// 持有 l.mu 期间不会产生新日志，排空后旧通路中不再有待写日志
+	if err := l.Drain(); err != nil {
+		l.deferError(err)
+	}
+
+	l.outMu.Lock()
+	atomic.StoreInt32((*int32)(&l.colorMode), int32(p.color))
+	l.setOut(p.out)
+	oldClosers := l.closers
+	l.closers = p.closers
+	l.outMu.Unlock()
+
+	if cfg.Level != "" {
+		l.logLevel.SetLevel(p.level)
+	}
+	l.encoder = p.encoder
+	l.logFormatFunc = nil
+	l.cache.use = p.cache
+	l.overflow = p.overflow
+	l.tenants = p.tenants
+	l.separator = p.sep
+	l.mem.limit = cfg.MemoryLimit
+	l.mem.degraded = l.mem.degraded && cfg.MemoryLimit > 0
+	if cfg.Source != "" {
+		l.source = cfg.Source
+	}
+	if !l.started {
+		if d, _ := parseConfigDuration(cfg.CacheDuration); d > 0 {
+			l.cache.duration = d / time.Millisecond
+		}
+		if cfg.QueueSize > 0 {
+			l.queueSize = cfg.QueueSize
+		}
+	}
+
+	oldSinks := l.sinks
+	l.setSinks(p.sinks)
+	for _, s := range p.sinks {
+		s.onError = l.handleError
+		s.clock = l.clock
+		s.start()
+	}
+	l.mu.Unlock()
+	l.reportErrors()
+
+	// 旧的附加输出端写完缓冲后关闭，旧输出在排空后已不再使用
+	var errs []error
+	for _, s := range oldSinks {
+		errs = append(errs, s.close())
+	}
+	for _, c := range oldClosers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// 按配置构建输出通路，调用方需先检查配置
+func buildPipeline(cfg Config) (p *pipeline, err error) {
+	p = &pipeline{}
+	defer func() {
+		if err != nil {
+			for _, c := range p.closers {
+				c.Close()
+			}
+		}
+	}()
+
+	if cfg.Level != "" {
+		if p.level, err = ParseLogType(cfg.Level); err != nil {
+			return nil, err
+		}
+	}
+	if p.overflow, err = ParseOverflowPolicy(cfg.Overflow); err != nil {
+		return nil, err
+	}
+	p.color, _ = parseColorMode(cfg.Color)
+	if p.sep, err = ParseSeparator(cfg.Separator); err != nil {
+		return nil, err
+	}
+	tp, err := tenantPolicies(cfg.Tenants)
+	if err != nil {
+		return nil, err
+	}
+	if tp != nil {
+		p.tenants = newTenantState(*tp)
+	}
+
+	// 主输出，文件输出按切换配置写入目录
+	scheme, path, err := parseOutputURL(cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+	if networkSchemes[scheme] {
+		return nil, fmt.Errorf("logger: %s output is only supported for sinks", scheme)
+	}
+	p.cache = scheme != "file"
+	switch scheme {
+	case "stdout":
+		p.out = os.Stdout
+	case "stderr":
+		p.out = os.Stderr
+	case "file":
+		opts, err := rotateOptions(cfg.Rotate)
+		if err != nil {
+			return nil, err
+		}
+		f, err := newRotateFileLogger(path, opts...)
+		if err != nil {
+			return nil, err
+		}
+		p.out = f
+		p.closers = append(p.closers, f)
+	}
+	if cfg.Mode != "" {
+		p.cache = cfg.Mode == "cache"
+	}
+	p.encoder = configEncoder(cfg.Encoding, scheme != "file")
+
+	// 附加输出端
+	for _, sc := range cfg.Sinks {
+		s := &Sink{
+			Name:        sc.URL,
+			BufferSize:  sc.BufferSize,
+			AllowFields: sc.AllowFields,
+			DenyFields:  sc.DenyFields,
+		}
+		if s.BufferSize <= 0 {
+			s.BufferSize = defaultSinkBufferSize
+		}
+		if sc.Level != "" {
+			if s.MinLevel, err = ParseLogType(sc.Level); err != nil {
+				return nil, err
+			}
+		}
+		if s.Separator, err = ParseSeparator(sc.Separator); err != nil {
+			return nil, err
+		}
+		if sc.Chaos != nil {
+			if s.Chaos, err = sc.Chaos.chaos(); err != nil {
+				return nil, err
+			}
+		}
+		scheme, path, err := parseOutputURL(sc.URL)
+		if err != nil {
+			return nil, err
+		}
+		switch scheme {
+		case "stdout":
+			s.Writer = os.Stdout
+		case "stderr":
+			s.Writer = os.Stderr
+		case "file":
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+			if err != nil {
+				return nil, err
+			}
+			s.Writer = f
+			p.closers = append(p.closers, f)
+		case "tcp", "udp":
+			w := NewNetSink(scheme, path)
+			s.Writer, s.Encoder = w, w
+			p.closers = append(p.closers, w)
+		case "syslog", "syslog+tcp":
+			cfg := SyslogConfig{Addr: path}
+			if path != "" {
+				cfg.Network = strings.TrimPrefix(strings.TrimPrefix(scheme, "syslog"), "+")
+				if cfg.Network == "" {
+					cfg.Network = "udp"
+				}
+			}
+			w := NewSyslogSink(cfg)
+			s.Writer, s.Encoder = w, w
+			p.closers = append(p.closers, w)
+		}
+		if sc.Encoding != "" {
+			s.Encoder = configEncoder(sc.Encoding, scheme != "file")
+		}
+		p.sinks = append(p.sinks, s)
+	}
+
+	return p, nil
+}
+
+// 按名称创建编码器，终端输出的文本带颜色
+func configEncoder(name string, terminal bool) Encoder {
+	if name == "json" {
+		return NewJSONEncoder()
+	}
+	return &TextEncoder{Color: terminal}
+}
+
+// 将切换配置转为选项
+func rotateOptions(r RotateConfig) ([]RotateOption, error) {
+	interval, ok := parseConfigDuration(r.Interval)
+	if !ok {
+		return nil, fmt.Errorf("logger: invalid rotate interval %q", r.Interval)
+	}
+	slice, ok := parseConfigDuration(r.Slice)
+	if !ok {
+		return nil, fmt.Errorf("logger: invalid rotate slice %q", r.Slice)
+	}
+	age, ok := parseConfigDuration(r.MaxAge)
+	if !ok {
+		return nil, fmt.Errorf("logger: invalid rotate max age %q", r.MaxAge)
+	}
+	enc, err := ParseFileEncoding(r.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return []RotateOption{
+		WithRotateInterval(interval),
+		WithTimeSlice(slice),
+		WithMaxSize(r.MaxSize),
+		WithMaxAge(age),
+		WithMaxBackups(r.MaxBackups),
+		WithCompress(r.Compress),
+		WithBufferSize(r.BufferSize),
+		WithRotateMarker(r.Marker),
+		WithFileEncoding(enc),
+	}, nil
+}
+
+// 关闭通路创建的文件
+func (l *Logger) closeClosers() error {
+	l.outMu.Lock()
+	closers := l.closers
+	l.closers = nil
+	l.outMu.Unlock()
+
+	var errs []error
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("logger: close %s: %w", writerName(c), err))
+		}
+	}
+	return joinErrors(errs...)
+}
@@ -0,0 +1,66 @@
+package logger
+
+import "context"
+
+// 日志调用的处理结果
+type LogResult int
+
+const (
+	LogAccepted = LogResult(iota) // 已进入缓存或队列，等待写出
+	LogFiltered                   // 低于日志级别或被格式化函数过滤
+	LogSampled                    // 被重复日志采样或租户采样抑制
+	LogDropped                    // 队列满按处理策略丢弃
+	LogClosed                     // 日志已关闭或为零值日志
+	LogInvalid                    // 不支持的日志类型或编码失败
+)
+
+var logResultStrings = map[LogResult]string{
+	LogAccepted: "accepted",
+	LogFiltered: "filtered",
+	LogSampled:  "sampled",
+	LogDropped:  "dropped",
+	LogClosed:   "closed",
+	LogInvalid:  "invalid",
+}
+
+/*
+ * 输出一条日志并返回处理结果
+ *
+ * 与 Info 等日志方法相同，kvs 为交替的键值对。关键日志可据结果采取补救措施：
+ *
+ *   if r := l.Log(logger.ERROR, "payment failed", "order", id); !r.OK() {
+ *       fmt.Fprintln(os.Stderr, "payment failed", id, r)
+ *   }
+ *
+ * 结果只反映主输出，附加输出端(AddSink)各自计数丢弃条数，见 Sink.Dropped。
+ */
+func (l *Logger) Log(level LogType, msg interface{}, kvs ...interface{}) LogResult {
+	return l.log(level, messageArg(msg), kvsToFields(kvs)...)
+}
+
+/*
+ * 以 pc 为调用位置输出一条日志，附加 ctx 中的字段(见 SetContextExtractor)，返回处理结果
+ *
+ * 供适配其他日志接口的封装使用，如 contrib/gormlogger 以 gorm 之外的第一个栈帧为调用位置，
+ * 开启 SetReportCaller 时输出业务代码的位置而不是封装函数。pc 为 0 时调用位置为 LogAt 的调用方，ctx 可为 nil。
+ */
+func (l *Logger) LogAt(ctx context.Context, pc uintptr, level LogType, msg interface{}, kvs ...interface{}) LogResult {
+	if pc == 0 {
+		pc = callerPC(1)
+	}
+	fields := l.levelContextFields(ctx, level)
+	fields = append(fields[:len(fields):len(fields)], kvsToFields(kvs)...)
+	return l.logAt(pc, level, messageArg(msg), fields)
+}
+
+// 日志是否已被接受
+func (r LogResult) OK() bool {
+	return r == LogAccepted
+}
+
+func (r LogResult) String() string {
+	if s, ok := logResultStrings[r]; ok {
+		return s
+	}
+	return "unknown"
+}
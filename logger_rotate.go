@@ -0,0 +1,307 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 切换后旧文件末尾标记行的前缀，见 WithRotateMarker
+const RotateMarker = "#logger-rotated"
+
+// 文件切换选项
+type RotateOption func(*RotateFileLogger)
+
+// 大小、压缩与保留策略
+type rotatePolicy struct {
+	maxSize    int64         // 单个文件大小上限(字节)，0 表示不限
+	maxAge     time.Duration // 旧文件保留时长，0 表示不限
+	maxBackups int           // 旧文件保留个数，0 表示不限
+	compress   bool          // 是否 gzip 压缩旧文件
+	marker     bool          // 是否在旧文件末尾写入标记行
+}
+
+// 单个文件超过 size 字节时切换新文件
+func WithMaxSize(size int64) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.policy.maxSize = size
+	}
+}
+
+// 写入经过 size 字节的缓冲，多条日志合并为一次磁盘写入
+// 缓冲在队列空闲、Flush、切换文件和关闭时写到文件，0 表示不使用缓冲
+func WithBufferSize(size int) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.bufSize = size
+	}
+}
+
+// 按时间切换文件，如 time.Hour(每小时)、24*time.Hour(每天)，同 SetNewFileGapTime
+func WithRotateInterval(interval time.Duration) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.newFileGapTime = interval
+	}
+}
+
+/*
+ * 时间切片：从创建时起每隔 interval 开始一个新文件，不论文件大小，文件名带补零的序号
+ *
+ *   l := NewRotateFileLogger("./loadtest", WithTimeSlice(30*time.Second))
+ *   // 2006-01-02.0001.log 为前 30 秒，2006-01-02.0002.log 为其后 30 秒，以此类推
+ *
+ * 序号由经过的时间计算，没有日志的切片不创建文件，序号随之跳过，便于按压测阶段归集日志。
+ * 与 WithRotateInterval 同时使用时以时间切片为准，WithMaxSize 仍然生效。
+ */
+func WithTimeSlice(interval time.Duration) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.slice.interval = interval
+	}
+}
+
+// 删除修改时间早于 age 的旧文件
+func WithMaxAge(age time.Duration) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.policy.maxAge = age
+	}
+}
+
+// 最多保留 n 个旧文件
+func WithMaxBackups(n int) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.policy.maxBackups = n
+	}
+}
+
+// 切换后 gzip 压缩旧文件
+func WithCompress(compress bool) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.policy.compress = compress
+	}
+}
+
+// 时间切片的状态
+type timeSlice struct {
+	interval time.Duration // 切片长度，0 表示不按切片切换
+	start    time.Time     // 第一个切片的开始时间
+	base     string        // 不带序号的文件名
+	seq      int           // 当前切片的序号，从 1 开始
+}
+
+// 开始第一个切片，返回其文件名
+func (s *timeSlice) begin(now time.Time, base string) string {
+	s.start, s.base, s.seq = now, base, 1
+	return s.filename()
+}
+
+// now 进入新的切片时返回新切片的文件名
+func (s *timeSlice) next(now time.Time) (string, bool) {
+	if s.interval <= 0 {
+		return "", false
+	}
+	seq := int(now.Sub(s.start)/s.interval) + 1
+	if seq <= s.seq {
+		return "", false
+	}
+	s.seq = seq
+	return s.filename(), true
+}
+
+// 序号补零到 4 位，插在扩展名之前
+func (s *timeSlice) filename() string {
+	ext := filepath.Ext(s.base)
+	return fmt.Sprintf("%s.%04d%s", strings.TrimSuffix(s.base, ext), s.seq, ext)
+}
+
+/*
+ * 切换文件时在旧文件末尾写入一行标记，记录继续写入的文件与切换时间：
+ *
+ *   #logger-rotated next=app.log ts=2006-01-02T15:04:05.999999999+08:00
+ *
+ * 切换时旧文件先写出缓冲并同步到磁盘，再改名或关闭。跟踪文件的采集程序读到以 RotateMarker
+ * 开头的行即可确认旧文件已完整交接，没有标记而文件变短或被替换则说明是截断或异常退出。
+ * 标记行不是日志，ParseRecord 等解析时会跳过。
+ */
+func WithRotateMarker(marker bool) RotateOption {
+	return func(l *RotateFileLogger) {
+		l.policy.marker = marker
+	}
+}
+
+// 标记行
+func rotateMarkerLine(next string, t time.Time) string {
+	return RotateMarker + " next=" + next + " ts=" + t.Format(time.RFC3339Nano) + "\n"
+}
+
+// 按间隔对齐文件时间，间隔为整小时/整天时对齐到本地时间的整点/零点
+func alignFileTime(t time.Time, gap time.Duration) time.Time {
+	if gap <= 0 {
+		return t
+	}
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(gap).Add(-shift)
+}
+
+// 超过大小上限时将当前文件改名备份并重新创建。调用方需持有输出锁
+func (l *RotateFileLogger) rotateBySize(now time.Time) error {
+	current := l.filePath
+	ext := filepath.Ext(current)
+	prefix := strings.TrimSuffix(current, ext) + "." + now.Format("20060102T150405.000")
+	backup := prefix + ext
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		// 同一毫秒内多次切换时追加序号
+		backup = prefix + "-" + strconv.Itoa(i) + ext
+	}
+
+	// 旧文件写出并同步到磁盘后再改名，跟踪文件的采集程序不会读到不完整的内容
+	// 关闭失败时文件句柄同样已关闭，仍继续切换
+	cerr := l.closeFile(filepath.Base(current))
+	l.file = nil
+	if err := os.Rename(current, backup); err != nil {
+		// 改名失败时重新打开当前文件继续写入
+		return joinErrors(cerr, err, l.openFile(filepath.Base(current)))
+	}
+	if err := l.openFile(filepath.Base(current)); err != nil {
+		return joinErrors(cerr, err)
+	}
+
+	l.cleanup(backup)
+	return cerr
+}
+
+// 后台压缩刚切换下来的旧文件，并按保留策略清理
+func (l *RotateFileLogger) cleanup(rotated string) {
+	if !l.policy.compress && l.policy.maxAge == 0 && l.policy.maxBackups == 0 {
+		return
+	}
+
+	active := l.filePath
+	pattern := l.backupPattern()
+	l.cleanWG.Add(1)
+	go func() {
+		defer l.cleanWG.Done()
+		l.cleanMu.Lock()
+		defer l.cleanMu.Unlock()
+
+		if l.policy.compress && rotated != "" {
+			// 旧文件可能已按保留策略删除
+			if err := compressFile(rotated); err != nil && !os.IsNotExist(err) {
+				l.handleError(err)
+			}
+		}
+		if err := l.removeExpired(active, pattern); err != nil {
+			l.handleError(err)
+		}
+	}()
+}
+
+// 按大小切换的旧文件在文件名与扩展名之间的时间后缀，如 ".20060102T150405.000-1"
+const backupSuffixPattern = `(\.\d{8}T\d{6}\.\d{3}(-\d+)?)?`
+
+var digitsPattern = regexp.MustCompile(`\d+`)
+
+// 本日志产生的文件名：按文件名格式生成的名称中每段数字可为任意数字，之后可有时间切片序号与
+// 按大小切换的时间后缀，可再加 .gz。目录中其他程序的日志不会匹配。调用方需持有输出锁
+func (l *RotateFileLogger) backupPattern() *regexp.Regexp {
+	name := l.fileNameFormatFunc(l.lastFileTime)
+	ext := filepath.Ext(name)
+	stem := digitsPattern.ReplaceAllString(regexp.QuoteMeta(strings.TrimSuffix(name, ext)), `\d+`)
+	slice := ""
+	if l.slice.interval > 0 {
+		slice = `(\.\d{4,})?`
+	}
+	return regexp.MustCompile("^" + stem + slice + backupSuffixPattern + regexp.QuoteMeta(ext) + `(\.gz)?$`)
+}
+
+// 按保留时长和个数删除本日志的旧文件，pattern 见 backupPattern
+func (l *RotateFileLogger) removeExpired(active string, pattern *regexp.Regexp) error {
+	if l.policy.maxAge == 0 && l.policy.maxBackups == 0 {
+		return nil
+	}
+
+	dir := l.dirPath
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	backups := []backup{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == filepath.Base(active) {
+			continue
+		}
+		if !pattern.MatchString(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), modTime: info.ModTime()})
+	}
+
+	// 新文件在前
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	now := time.Now()
+	for i, b := range backups {
+		expired := l.policy.maxAge > 0 && now.Sub(b.modTime) > l.policy.maxAge
+		overflow := l.policy.maxBackups > 0 && i >= l.policy.maxBackups
+		if expired || overflow {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// gzip 压缩文件并删除原文件
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
+
+// 文件是否存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+ * 重复日志采样配置
+ *
+ *   l.SetSampler(logger.SamplerConfig{Initial: 10, Thereafter: 100, Window: time.Second})
+ *
+ * 每个窗口内，相同的日志(默认按级别+消息判断)先输出 Initial 条，之后每 Thereafter 条输出一条，
+ * 其余的被抑制；窗口结束时输出一条 "suppressed N similar messages: ..." 汇总，级别与原日志相同。
+ */
+type SamplerConfig struct {
+	Initial    int           // 每个窗口内先输出的条数
+	Thereafter int           // 超过 Initial 后每隔多少条输出一条，0 表示不再输出
+	Window     time.Duration // 窗口长度，0 使用默认值 1 秒
+	// 自定义判断重复的 key，为 nil 时使用级别+消息
+	Key func(level LogType, msg string, fields []Field) string
+}
+
+// 重复日志采样器，由根日志的 l.mu 保护
+type sampler struct {
+	cfg   SamplerConfig
+	state map[string]*sampleState
+}
+
+// 一种日志在当前窗口内的计数
+type sampleState struct {
+	start      time.Time // 窗口开始时间
+	count      int       // 窗口内出现的次数
+	suppressed int       // 窗口内抑制的条数
+	level      LogType
+	msg        string
+}
+
+// 设置重复日志采样，Initial 与 Thereafter 均为 0 时关闭。派生日志设置根日志
+// 被抑制的条数见 Stats().Suppressed
+func (l *Logger) SetSampler(cfg SamplerConfig) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetSampler(cfg)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cfg.Initial == 0 && cfg.Thereafter == 0 {
+		l.sampler = nil
+		return
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Second
+	}
+	l.sampler = &sampler{cfg: cfg, state: map[string]*sampleState{}}
+}
+
+// 判断一条日志是否输出，窗口已结束且有抑制的日志时返回上一窗口的汇总
+func (s *sampler) sample(level LogType, i interface{}, fields []Field, now time.Time) (keep bool, summary *sampleState) {
+	msg := sampleMessage(i)
+	key := ""
+	if s.cfg.Key != nil {
+		key = s.cfg.Key(level, msg, fields)
+	} else {
+		key = GetLogTypeString(level) + msg
+	}
+
+	st, ok := s.state[key]
+	if ok && now.Sub(st.start) >= s.cfg.Window {
+		if st.suppressed > 0 {
+			summary = st
+		}
+		ok = false
+	}
+	if !ok {
+		st = &sampleState{start: now, level: level, msg: msg}
+		s.state[key] = st
+	}
+
+	st.count++
+	if st.count <= s.cfg.Initial ||
+		(s.cfg.Thereafter > 0 && (st.count-s.cfg.Initial)%s.cfg.Thereafter == 0) {
+		return true, summary
+	}
+	st.suppressed++
+	return false, summary
+}
+
+// 取出已结束窗口(all 为 true 时为全部窗口)的汇总并清理
+func (s *sampler) expired(now time.Time, all bool) []*sampleState {
+	var summaries []*sampleState
+	for key, st := range s.state {
+		if !all && now.Sub(st.start) < s.cfg.Window {
+			continue
+		}
+		if st.suppressed > 0 {
+			summaries = append(summaries, st)
+		}
+		delete(s.state, key)
+	}
+	return summaries
+}
+
+// 输出抑制汇总，调用方需持有 l.mu
+func (l *Logger) emitSummary(st *sampleState) {
+	msg := fmt.Sprintf("suppressed %d similar messages: %s", st.suppressed, st.msg)
+	l.emit("", time.Time{}, st.level, msg, nil, nil)
+}
+
+// 输出已结束窗口的抑制汇总，由后台检查定期调用；关闭日志时 all 为 true，输出全部汇总
+func (l *Logger) flushSamples(all bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sampler == nil || l.closed {
+		return
+	}
+	for _, st := range l.sampler.expired(time.Now(), all) {
+		l.emitSummary(st)
+	}
+}
+
+// 用于判断重复的消息文本
+func sampleMessage(i interface{}) string {
+	switch v := i.(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, " | ")
+	case *AccessEntry:
+		return v.message()
+	case *DiffEntry:
+		return v.Message
+	}
+	return fmt.Sprint(i)
+}
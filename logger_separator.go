@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// 日志记录之间的分隔方式。UDP 与 syslog 输出按换行拆分数据报，应使用默认的换行
+type Separator int
+
+const (
+	SeparatorLF     = Separator(0) // 换行 "\n"(默认)
+	SeparatorCRLF   = Separator(1) // 回车换行 "\r\n"，供 Windows 工具读取
+	SeparatorNUL    = Separator(2) // NUL 字节，供 xargs -0 等按 NUL 分隔的消费方读取，记录中可含换行
+	SeparatorLength = Separator(3) // 4 字节大端长度前缀，不带结尾分隔符
+)
+
+var separatorNames = map[Separator]string{
+	SeparatorLF:     "lf",
+	SeparatorCRLF:   "crlf",
+	SeparatorNUL:    "nul",
+	SeparatorLength: "length",
+}
+
+func (s Separator) String() string {
+	if name, ok := separatorNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// 由名称解析分隔方式，空串为 SeparatorLF
+func ParseSeparator(s string) (Separator, error) {
+	if s == "" {
+		return SeparatorLF, nil
+	}
+	for sep, name := range separatorNames {
+		if name == s {
+			return sep, nil
+		}
+	}
+	return SeparatorLF, fmt.Errorf("logger: unknown separator %q", s)
+}
+
+// 将编码器结尾的换行替换为分隔符
+func (s Separator) apply(buf *Buffer) {
+	if s == SeparatorLF {
+		return
+	}
+	if n := len(buf.b); n > 0 && buf.b[n-1] == '\n' {
+		buf.b = buf.b[:n-1]
+	}
+	switch s {
+	case SeparatorCRLF:
+		buf.b = append(buf.b, '\r', '\n')
+	case SeparatorNUL:
+		buf.b = append(buf.b, 0)
+	case SeparatorLength:
+		n := len(buf.b)
+		buf.b = append(buf.b, 0, 0, 0, 0)
+		copy(buf.b[4:], buf.b[:n])
+		binary.BigEndian.PutUint32(buf.b, uint32(n))
+	}
+}
+
+// 设置主输出的记录分隔方式，附加输出端见 Sink.Separator。派生日志设置根日志
+func (l *Logger) SetSeparator(sep Separator) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetSeparator(sep)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.separator = sep
+}
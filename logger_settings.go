@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultCacheDuration = 100    // 默认的缓存同步周期(毫秒)
+	defaultCacheCap      = 128    // 默认的缓存容量(条)
+	defaultQueueSize     = 100000 // 默认的队列大小(条)
+	minQueueSize         = 1000   // 队列模式建议的最小队列大小
+	typicalEntrySize     = 256    // 估算内存占用时每条日志的字节数
+	maxEarlyEntries      = 10000  // 启动前缓存的日志条数上限
+)
+
+// 设置有误或可能导致阻塞、忙等，可通过 errors.Is 判断
+var ErrBadSettings = errors.New("logger: bad settings")
+
+/*
+ * 检查缓存、队列与内存上限的设置，返回每个问题及建议值，无问题时返回 nil
+ *
+ *   for _, err := range l.CheckSettings() {
+ *       log.Println(err) // logger: bad settings: queue size 0 with cache off blocks ...
+ *   }
+ *
+ * Start 时同样检查并将问题交给错误处理函数：无法运行的设置(如周期为 0、队列大小为负)
+ * 改用默认值后继续启动，其他设置保持不变。
+ */
+func (l *Logger) CheckSettings() []error {
+	if l.noop() {
+		return nil
+	}
+
+	if l.root != nil {
+		return l.root.CheckSettings()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.checkSettings(false)
+}
+
+// 检查设置，fix 为 true 时将无法运行的设置改为默认值。调用方需持有 l.mu
+func (l *Logger) checkSettings(fix bool) []error {
+	var errs []error
+	report := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]interface{}{ErrBadSettings}, args...)...))
+	}
+
+	switch d := l.cache.duration; {
+	case d <= 0:
+		report("cache duration %d is not positive, the flush ticker cannot run; use SetCacheDuration(%d)", d, defaultCacheDuration)
+		if fix {
+			l.cache.duration = defaultCacheDuration
+		}
+	case d >= time.Millisecond:
+		// 周期以毫秒计，传入 time.Duration 时实际周期放大了一百万倍
+		report("cache duration is in milliseconds, SetCacheDuration(%d) flushes every %s; use SetCacheDuration(%d)",
+			int64(d), time.Millisecond*d, int64(d/time.Millisecond))
+	}
+
+	if l.cache.cacheCap <= 0 {
+		report("cache capacity %d is not positive; use SetCacheCap(%d)", l.cache.cacheCap, defaultCacheCap)
+		if fix {
+			l.cache.cacheCap = defaultCacheCap
+		}
+	}
+
+	switch {
+	case l.queueSize < 0:
+		report("queue size %d is negative; use SetQueueSize(%d)", l.queueSize, defaultQueueSize)
+		if fix {
+			l.queueSize = defaultQueueSize
+		}
+	case l.queueSize == 0 && !l.cache.use && l.overflow == OverflowBlock:
+		report("queue size 0 with cache off blocks every log call until it is written; use SetQueueSize(%d) or larger", minQueueSize)
+	case l.queueSize == 0 && !l.cache.use:
+		report("queue size 0 with cache off drops nearly every entry under overflow policy %s; use SetQueueSize(%d) or larger", l.overflow, minQueueSize)
+	}
+
+	if need := int64(l.cache.cacheCap) * typicalEntrySize; l.mem.limit > 0 && l.cache.use && need > l.mem.limit {
+		report("memory limit %d is below the cache capacity of %d entries (about %d bytes), so logging runs synchronously; use SetMemoryLimit(%d) or SetCacheCap(%d)",
+			l.mem.limit, l.cache.cacheCap, need, need, l.mem.limit/typicalEntrySize)
+	}
+	return errs
+}
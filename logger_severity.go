@@ -0,0 +1,74 @@
+package logger
+
+// JSON 输出严重性名称字段的键，见 JSONEncoder.Severity
+const SeverityKey = "severity"
+
+/*
+ * 日志级别到下游系统严重性的映射
+ *
+ * 内置 SyslogSeverities、GCPSeverities、OTelSeverities 与 SystemdPriorities，新的输出端直接复用，
+ * 不必各自维护略有出入的映射表。需要调整时复制后修改，不影响内置映射：
+ *
+ *   p := logger.GCPSeverities.With(logger.FATAL, 800, "EMERGENCY")
+ *   l.AddSink(&logger.Sink{Writer: w, Encoder: &logger.JSONEncoder{Severity: &p}})
+ *
+ * 超出范围的级别按 FATAL 映射。
+ */
+type SeverityProfile struct {
+	Name    string            // 名称，如 "syslog"
+	Numbers [FATAL + 1]int    // 各级别的数值
+	Texts   [FATAL + 1]string // 各级别的名称
+}
+
+// syslog 严重性(RFC 5424)，FATAL 映射为 alert
+var SyslogSeverities = SeverityProfile{
+	Name:    "syslog",
+	Numbers: [...]int{DEBUG: 7, INFO: 6, NOTICE: 5, WARN: 4, ERROR: 3, CRITICAL: 2, FATAL: 1},
+	Texts:   [...]string{DEBUG: "debug", INFO: "info", NOTICE: "notice", WARN: "warning", ERROR: "err", CRITICAL: "crit", FATAL: "alert"},
+}
+
+// Google Cloud Logging 的 LogSeverity，FATAL 映射为 ALERT
+var GCPSeverities = SeverityProfile{
+	Name:    "gcp",
+	Numbers: [...]int{DEBUG: 100, INFO: 200, NOTICE: 300, WARN: 400, ERROR: 500, CRITICAL: 600, FATAL: 700},
+	Texts:   [...]string{DEBUG: "DEBUG", INFO: "INFO", NOTICE: "NOTICE", WARN: "WARNING", ERROR: "ERROR", CRITICAL: "CRITICAL", FATAL: "ALERT"},
+}
+
+// OpenTelemetry 的 SeverityNumber 与 SeverityText，NOTICE 与 CRITICAL 分别为 INFO2、ERROR2
+var OTelSeverities = SeverityProfile{
+	Name:    "otel",
+	Numbers: [...]int{DEBUG: 5, INFO: 9, NOTICE: 10, WARN: 13, ERROR: 17, CRITICAL: 18, FATAL: 21},
+	Texts:   [...]string{DEBUG: "DEBUG", INFO: "INFO", NOTICE: "INFO2", WARN: "WARN", ERROR: "ERROR", CRITICAL: "ERROR2", FATAL: "FATAL"},
+}
+
+// systemd 的优先级，名称为写入标准错误时 journald 识别的行首前缀
+var SystemdPriorities = SeverityProfile{
+	Name:    "systemd",
+	Numbers: [...]int{DEBUG: 7, INFO: 6, NOTICE: 5, WARN: 4, ERROR: 3, CRITICAL: 2, FATAL: 1},
+	Texts:   [...]string{DEBUG: "<7>", INFO: "<6>", NOTICE: "<5>", WARN: "<4>", ERROR: "<3>", CRITICAL: "<2>", FATAL: "<1>"},
+}
+
+// 级别对应的数值
+func (p *SeverityProfile) Number(level LogType) int {
+	return p.Numbers[p.index(level)]
+}
+
+// 级别对应的名称
+func (p *SeverityProfile) Text(level LogType) string {
+	return p.Texts[p.index(level)]
+}
+
+// 返回修改了一个级别映射的副本
+func (p SeverityProfile) With(level LogType, number int, text string) SeverityProfile {
+	i := p.index(level)
+	p.Numbers[i], p.Texts[i] = number, text
+	return p
+}
+
+// 级别在映射表中的下标，超出范围按 FATAL
+func (p *SeverityProfile) index(level LogType) int {
+	if level < DEBUG || level > FATAL {
+		return int(FATAL)
+	}
+	return int(level)
+}
@@ -0,0 +1,397 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSinkBufferSize = 10000       // 输出端默认的待写条数上限
+	sinkRetryInterval     = time.Second // 写出失败后重试的间隔
+)
+
+/*
+ * 附加输出端
+ *
+ * 日志除写入主输出(SetOutput)外，同时按输出端各自的级别和编码器写入附加输出端：
+ *
+ *   l.AddOutput(file, logger.ERROR)                                   // ERROR 及以上写文件
+ *   l.AddSink(&logger.Sink{Writer: conn, MinLevel: logger.WARN, Encoder: logger.NewJSONEncoder()})
+ *   l.AddSink(&logger.Sink{Writer: os.Stdout, AllowFields: []string{"request_id"}}) // 控制台只显示少量字段
+ *
+ * 每个输出端有独立的缓冲和写出goroutine，慢的输出端不会阻塞日志调用和其他输出端；
+ * 待写条数超过 BufferSize 时丢弃新日志并计数，见 Dropped。
+ *
+ * 写出失败(输出端故障)时日志留在内存积压中，下次写出时按顺序重试；积压超过 CompressThreshold
+ * 后较旧的批次在内存中 gzip 压缩，超过 BacklogBytes 后才开始丢弃。Writer 返回的错误链中有
+ * Permanent() 为 true 的错误(如请求被拒绝)时该批次不再重试，直接丢弃并计数。
+ * 因故障推迟写出的日志保留原有时间，补发时追加 replayed=true 与补发时间 replayed_at(仅按行分隔时)。
+ */
+type Sink struct {
+	Name       string    // 名称，用于错误信息，为空时按输出推断(文件名、网络地址等)
+	Writer     io.Writer // 输出
+	MinLevel   LogType   // 最低输出级别
+	Encoder    Encoder   // 编码器，为 nil 时使用日志的编码器
+	BufferSize int       // 待写条数上限，0 使用默认值 10000
+	Separator  Separator // 记录分隔方式，默认换行
+
+	AllowFields []string // 只输出这些字段，为空表示全部输出
+	DenyFields  []string // 不输出这些字段，优先于 AllowFields
+
+	CompressThreshold int // 积压超过该字节数后压缩较旧的批次，0 表示不压缩
+	BacklogBytes      int // 积压占用内存上限(字节)，0 使用默认值 32MB
+
+	Events EventRoute // 业务事件的去向，见 Logger.Event
+	Chaos  *Chaos     // 故障注入，仅用于测试，见 Chaos
+
+	mu      sync.Mutex    // 待写缓冲的互斥锁
+	pending []queued      // 待写日志
+	writeMu sync.Mutex    // 写出时的互斥锁，保证批次顺序
+	notify  chan struct{} // 有新日志的信号
+	done    chan struct{} // 停止信号
+	wg      sync.WaitGroup
+	dropped int64 // 缓冲满或积压超限时丢弃的条数
+	expired int64 // 过期丢弃的条数
+	tty     bool  // 输出是否为终端
+	closed  int32 // 输出已不可用而停用
+
+	backlog     []backlogBatch // 写出失败后积压的批次，由 writeMu 保护
+	backlogSize int            // 积压占用的字节数
+
+	pendingBytes   int64      // 待写日志的字节数，用于内存上限
+	backlogBatches int64      // 积压的批次数，同 len(backlog)，可无锁读取
+	backlogBytes   int64      // 积压占用的字节数，同 backlogSize，可无锁读取
+	chaos          ChaosStats // 注入的故障次数，由 writeMu 保护
+
+	onError func(error) // 写出失败时的上报
+	clock   Clock       // 日志的时间来源，用于过期判断与重放标记
+}
+
+// 添加附加输出端，返回的 Sink 可用于 RemoveSink
+// w 同时实现 Encoder 时(如 NewSyslogSink)使用其自带的编码格式
+func (l *Logger) AddOutput(w io.Writer, minLevel LogType) *Sink {
+	s := &Sink{Writer: w, MinLevel: minLevel}
+	if enc, ok := w.(Encoder); ok {
+		s.Encoder = enc
+	}
+	l.AddSink(s)
+	return s
+}
+
+// 添加附加输出端并启动其写出goroutine。派生日志添加到根日志
+func (l *Logger) AddSink(s *Sink) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.AddSink(s)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s.BufferSize <= 0 {
+		s.BufferSize = defaultSinkBufferSize
+	}
+	s.onError = l.handleError
+	s.clock = l.clock
+	s.start()
+	l.setSinks(append(l.sinks, s))
+}
+
+// 移除附加输出端，写出其缓冲中的日志后停止
+func (l *Logger) RemoveSink(s *Sink) error {
+	if l.noop() {
+		return nil
+	}
+
+	if l.root != nil {
+		return l.root.RemoveSink(s)
+	}
+
+	l.mu.Lock()
+	found := false
+	for i, sink := range l.sinks {
+		if sink == s {
+			l.setSinks(append(l.sinks[:i:i], l.sinks[i+1:]...))
+			found = true
+			break
+		}
+	}
+	l.mu.Unlock()
+
+	if !found {
+		return nil
+	}
+	return s.close()
+}
+
+// 将一条日志分发到附加输出端，调用方需持有 l.mu
+// e 为 nil(自定义格式化函数)时各输出端均使用已格式化的 msg，msg 在返回后不再引用
+func (l *Logger) fanout(logType LogType, e *Entry, msg []byte, expire time.Time, event bool) {
+	for _, s := range l.sinks {
+		if !s.Events.accepts(event) {
+			continue
+		}
+		// 只接收业务事件的输出端不按级别过滤
+		if logType < s.MinLevel && !(event && s.Events == EventsOnly) {
+			continue
+		}
+		if s.disabled() {
+			atomic.AddInt64(&s.dropped, 1)
+			continue
+		}
+
+		buf := GetBuffer()
+		off := l.sinkColorOff(s)
+		if e != nil && (s.Encoder != nil || s.filtersFields()) {
+			enc := s.Encoder
+			if enc == nil {
+				enc = l.encoder
+			}
+			if err := encodeTo(l.colorEncoder(enc, off), buf, s.filterFields(e)); err != nil {
+				buf.Free()
+				l.deferError(err)
+				continue
+			}
+		} else {
+			buf.Write(msg)
+		}
+		if off {
+			if m := buf.String(); strings.IndexByte(m, '\033') >= 0 {
+				buf.Reset()
+				buf.WriteString(stripColor(m))
+			}
+		}
+		s.Separator.apply(buf)
+		if l.mem.degraded {
+			if err := s.writeSync(queued{buf: buf, expire: expire}); err != nil && !errors.Is(err, errNetBackoff) {
+				l.deferError(fmt.Errorf("logger: sink %s: %w", s.name(), err))
+			}
+			continue
+		}
+		if event {
+			s.enqueueEvent(queued{buf: buf, expire: expire, event: true})
+			continue
+		}
+		s.enqueue(queued{buf: buf, expire: expire})
+	}
+}
+
+// 写出全部附加输出端的缓冲，合并各输出端的错误
+func (l *Logger) flushSinks() error {
+	l.mu.Lock()
+	sinks := append([]*Sink(nil), l.sinks...)
+	l.mu.Unlock()
+
+	var errs []error
+	for _, s := range sinks {
+		if err := s.flush(); err != nil {
+			errs = append(errs, fmt.Errorf("logger: sink %s: %w", s.name(), err))
+		}
+	}
+	return joinErrors(errs...)
+}
+
+// 设置附加输出端并更新状态快照。调用方需持有 l.mu
+func (l *Logger) setSinks(sinks []*Sink) {
+	l.sinks = sinks
+	l.viewMu.Lock()
+	l.view.sinks = sinks
+	l.viewMu.Unlock()
+}
+
+// 附加输出端的快照，不需要持有 l.mu
+func (l *Logger) sinkView() []*Sink {
+	l.viewMu.Lock()
+	defer l.viewMu.Unlock()
+	return l.view.sinks
+}
+
+// 关闭全部附加输出端，合并各输出端的错误
+func (l *Logger) closeSinks() error {
+	l.mu.Lock()
+	sinks := l.sinks
+	l.setSinks(nil)
+	l.mu.Unlock()
+
+	var errs []error
+	for _, s := range sinks {
+		if err := s.close(); err != nil {
+			errs = append(errs, fmt.Errorf("logger: sink %s: %w", s.name(), err))
+		}
+	}
+	return joinErrors(errs...)
+}
+
+// 输出端名称
+func (s *Sink) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return writerName(s.Writer)
+}
+
+// 输出的描述：文件名、网络地址，其他按类型
+func writerName(w interface{}) string {
+	switch w := w.(type) {
+	case *NetSink:
+		return w.conn.name
+	case *SyslogSink:
+		return w.conn.name
+	case interface{ Name() string }:
+		return w.Name()
+	case fmt.Stringer:
+		return w.String()
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// 合并错误，跳过 nil 和信息相同的重复错误，无错误时返回 nil
+func joinErrors(errs ...error) error {
+	joined := make([]error, 0, len(errs))
+	seen := make(map[string]bool, len(errs))
+	for _, err := range errs {
+		if err == nil || seen[err.Error()] {
+			continue
+		}
+		seen[err.Error()] = true
+		joined = append(joined, err)
+	}
+	return errors.Join(joined...)
+}
+
+// 是否配置了字段过滤
+func (s *Sink) filtersFields() bool {
+	return len(s.AllowFields) > 0 || len(s.DenyFields) > 0
+}
+
+// 按允许/拒绝列表过滤条目的字段，未配置或无需过滤时返回原条目
+func (s *Sink) filterFields(e *Entry) *Entry {
+	if !s.filtersFields() || len(e.Fields) == 0 {
+		return e
+	}
+
+	fields := make([]Field, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		if containsString(s.DenyFields, f.Key) {
+			continue
+		}
+		if len(s.AllowFields) > 0 && !containsString(s.AllowFields, f.Key) {
+			continue
+		}
+		fields = append(fields, f)
+	}
+
+	filtered := *e
+	filtered.Fields = fields
+	return &filtered
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 缓冲满或积压超限时丢弃的条数
+func (s *Sink) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// 过期丢弃的条数
+func (s *Sink) Expired() int64 {
+	return atomic.LoadInt64(&s.expired)
+}
+
+// 启动写出goroutine
+func (s *Sink) start() {
+	s.tty = isTerminal(s.Writer) && enableVirtualTerminal(s.Writer)
+	s.notify = make(chan struct{}, 1)
+	s.done = make(chan struct{})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		// 写出失败后定时重试积压，输出端恢复(如网络重连)后无需等待新日志
+		var retry <-chan time.Time
+		for {
+			select {
+			case <-s.notify:
+			case <-retry:
+			case <-s.done:
+				return
+			}
+			retry = nil
+			if err := s.flush(); err != nil {
+				retry = time.After(sinkRetryInterval)
+				if !errors.Is(err, errNetBackoff) {
+					s.onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// 追加一条待写日志
+func (s *Sink) enqueue(q queued) {
+	s.mu.Lock()
+	if len(s.pending) >= s.BufferSize {
+		s.mu.Unlock()
+		atomic.AddInt64(&s.dropped, 1)
+		q.buf.Free()
+		return
+	}
+	s.pending = append(s.pending, q)
+	atomic.AddInt64(&s.pendingBytes, int64(q.buf.Len()))
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// 同步写出缓冲中的日志
+func (s *Sink) flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	atomic.AddInt64(&s.pendingBytes, -queuedBytes(pending))
+	s.mu.Unlock()
+
+	s.Chaos.reorder(pending, &s.chaos)
+	buf, _, expired := joinQueued(pending, s.clock)
+	if expired > 0 {
+		atomic.AddInt64(&s.expired, int64(expired))
+	}
+	if buf == nil && len(s.backlog) == 0 {
+		return nil
+	}
+	var msg []byte
+	if buf != nil {
+		msg = buf.Bytes()
+		defer buf.Free()
+	}
+	return s.writeWithBacklog(msg, len(pending)-expired)
+}
+
+// 停止写出goroutine并写出剩余日志
+func (s *Sink) close() error {
+	close(s.done)
+	s.wg.Wait()
+	return s.flush()
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+/*
+ * 生成 slog.Handler，使用 log/slog 的应用和库经由本日志的缓存/队列输出
+ *
+ *   slog.SetDefault(slog.New(logger.NewSlogHandler(l)))
+ *   slog.Info("request done", "status", 200)
+ *
+ * slog 级别映射为本日志级别(见 SlogLevel)，属性映射为字段，分组(WithGroup)以 "group.key" 作为字段名。
+ * 开启 SetReportCaller 时调用位置取自 slog 记录，即调用 slog 的位置。
+ */
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{l: l}
+}
+
+// 基于 Logger 的 slog.Handler
+type SlogHandler struct {
+	l      *Logger
+	prefix string // 分组前缀，如 "req."
+}
+
+// 声明接口实现者
+var (
+	_ slog.Handler = &SlogHandler{}
+)
+
+// 将 slog 级别映射为日志级别
+// 低于 Info 为 DEBUG，Info 与 Warn 之间偏高的级别为 NOTICE，高于 Error 为 CRITICAL 和 FATAL
+func SlogLevel(level slog.Level) LogType {
+	switch {
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelInfo+2:
+		return INFO
+	case level < slog.LevelWarn:
+		return NOTICE
+	case level < slog.LevelError:
+		return WARN
+	case level < slog.LevelError+4:
+		return ERROR
+	case level < slog.LevelError+8:
+		return CRITICAL
+	default:
+		return FATAL
+	}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return SlogLevel(level) >= h.l.GetLogLevel()
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, a)
+		return true
+	})
+	// 附加 ctx 中的字段，如 slog.InfoContext 传入的链路追踪信息
+	fields = append(fields, h.l.contextFields(ctx)...)
+	h.l.logAt(r.PC, SlogLevel(r.Level), r.Message, fields)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]Field, 0, len(attrs))
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, h.prefix, a)
+	}
+	return &SlogHandler{l: h.l.WithFields(fields...), prefix: h.prefix}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{l: h.l, prefix: h.prefix + name + "."}
+}
+
+// 将 slog 属性展开为字段，分组属性以 "group.key" 命名
+func appendSlogAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		// 匿名分组直接展开到当前层级
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range attrs {
+			fields = appendSlogAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	return append(fields, Field{Key: prefix + a.Key, Value: a.Value.Any()})
+}
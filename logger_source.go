@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+)
+
+// 来源字段的键
+const SourceKey = "source"
+
+// 日志自带的同名字段改用的键，与 JSON 编码器处理保留字段的方式一致
+const sourceFieldKey = "fields." + SourceKey
+
+var errEmptySource = errors.New("logger: source must not be empty")
+
+/*
+ * 设置日志来源，之后每条日志的第一个字段为 source(SourceKey)
+ *
+ *   if err := l.SetSource("billing-worker"); err != nil { ... }
+ *
+ * 多个进程或内嵌组件共用同一个网络或文件输出时，汇总后仍可按来源区分日志。
+ * 来源不能为空；派生日志继承派生时的来源，也可各自设置。日志自带同名字段(如 gorm 输出的代码位置)时
+ * 来源保持不变，日志的字段改名为 fields.source 输出，不输出重复的键。
+ */
+func (l *Logger) SetSource(source string) error {
+	if l.noop() {
+		return nil
+	}
+
+	if strings.TrimSpace(source) == "" {
+		return errEmptySource
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.source = source
+	return nil
+}
+
+// 获取日志来源
+func (l *Logger) GetSource() string {
+	if l.noop() {
+		return ""
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.source
+}
+
+// 合并来源、派生日志携带的字段与调用时的字段，调用方需持有 l.mu
+func (l *Logger) mergeFields(fields []Field) []Field {
+	if l.source == "" {
+		if len(l.fields) > 0 {
+			fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+		}
+		return fields
+	}
+	merged := make([]Field, 0, 1+len(l.fields)+len(fields))
+	merged = append(merged, Field{Key: SourceKey, Value: l.source})
+	for _, list := range [][]Field{l.fields, fields} {
+		for _, f := range list {
+			if f.Key == SourceKey {
+				f.Key = sourceFieldKey
+			}
+			merged = append(merged, f)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 日志统计快照
+type Stats struct {
+	Entries int64                // 输出条数
+	Bytes   int64                // 输出字节数
+	ByName  map[string]NameStats // 按日志名称(子系统)统计，用于成本归属
+	ByLevel map[LogType]int64    // 按级别统计的输出条数
+
+	LatencyViolations int64 // 超出写出延迟约定的批次数
+	Expired           int64 // 超过有效期未写出而丢弃的条数
+	Dropped           int64 // 队列满时按处理策略丢弃的条数
+	Suppressed        int64 // 重复日志采样或租户采样抑制的条数
+	SyncWrites        int64 // 超过内存上限时同步写出的条数，见 SetMemoryLimit
+	Memory            int64 // 缓存、队列与附加输出端占用的字节数
+
+	CallLatency Histogram // 日志调用耗时，未开启 SetCallLatency 时为零值
+}
+
+// 单个名称的统计
+type NameStats struct {
+	Entries int64 // 输出条数
+	Bytes   int64 // 输出字节数
+}
+
+// 统计计数器，同一日志及其派生日志共享
+type stats struct {
+	mu     sync.Mutex
+	byName map[string]*NameStats
+	levels [FATAL + 1]int64 // 按级别的输出条数
+	since  time.Time        // 开始统计的时间
+}
+
+func newStats() *stats {
+	return &stats{byName: map[string]*NameStats{}, since: time.Now()}
+}
+
+// 记录一条输出
+func (s *stats) add(name string, level LogType, bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if int(level) < len(s.levels) {
+		s.levels[level]++
+	}
+
+	ns, ok := s.byName[name]
+	if !ok {
+		ns = &NameStats{}
+		s.byName[name] = ns
+	}
+	ns.Entries++
+	ns.Bytes += int64(bytes)
+}
+
+// 累加一批日志，levels 为各级别的条数
+func (s *stats) addBatch(name string, levels [FATAL + 1]int64, bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns, ok := s.byName[name]
+	if !ok {
+		ns = &NameStats{}
+		s.byName[name] = ns
+	}
+	for i, n := range levels {
+		s.levels[i] += n
+		ns.Entries += n
+	}
+	ns.Bytes += int64(bytes)
+}
+
+// 生成快照
+func (s *stats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{ByName: make(map[string]NameStats, len(s.byName)), ByLevel: map[LogType]int64{}}
+	for name, ns := range s.byName {
+		st.Entries += ns.Entries
+		st.Bytes += ns.Bytes
+		st.ByName[name] = *ns
+	}
+	for level, n := range s.levels {
+		if n > 0 {
+			st.ByLevel[LogType(level)] = n
+		}
+	}
+	return st
+}
+
+// 设置日志名称，输出的条数与字节数按名称归属统计
+func (l *Logger) SetName(name string) {
+	if l.noop() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.name = name
+}
+
+// 获取日志名称
+func (l *Logger) GetName() string {
+	if l.noop() {
+		return ""
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.name
+}
+
+// 获取输出统计
+func (l *Logger) Stats() Stats {
+	if l.noop() {
+		return Stats{ByName: map[string]NameStats{}, ByLevel: map[LogType]int64{}}
+	}
+
+	if l.root != nil {
+		return l.root.Stats()
+	}
+
+	st := Stats{ByName: map[string]NameStats{}, ByLevel: map[LogType]int64{}}
+	if l.stats != nil {
+		st = l.stats.snapshot()
+	}
+	st.LatencyViolations = atomic.LoadInt64(&l.latency.violations)
+	st.Expired = atomic.LoadInt64(&l.expired)
+	st.Dropped = atomic.LoadInt64(&l.dropped)
+	st.Suppressed = atomic.LoadInt64(&l.suppressed)
+	st.SyncWrites = atomic.LoadInt64(&l.mem.syncWrites)
+	st.Memory = l.memoryUsage()
+	st.CallLatency = l.callLatency.snapshot()
+	return st
+}
+
+/*
+ * 设置关闭时是否输出运行汇总
+ *
+ * 开启后 Close 在写出全部日志前以 INFO 级别输出一条 "logger summary"，字段为：
+ * entries(总条数)、entries.<级别>(各级别条数)、bytes、dropped(队列满丢弃)、expired(过期丢弃)、
+ * suppressed(采样抑制)与 duration_ms(自创建日志起的运行时长)。汇总不受日志级别、租户与采样过滤。
+ * 批处理任务和命令行工具可据此在自身日志末尾得到可解析的运行汇总，JSON 输出时尤为方便。
+ */
+func (l *Logger) SetShutdownSummary(on bool) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetShutdownSummary(on)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.summary = on
+}
+
+// 输出运行汇总，统计不含汇总自身。汇总直接写出，不受级别、租户与采样过滤
+func (l *Logger) logSummary() {
+	l.mu.Lock()
+	on, closed := l.summary, l.closed
+	l.mu.Unlock()
+	if !on || closed {
+		return
+	}
+
+	st := l.Stats()
+	fields := []Field{{Key: "entries", Value: st.Entries}}
+	for level := DEBUG; level <= FATAL; level++ {
+		if n := st.ByLevel[level]; n > 0 {
+			key := "entries." + strings.ToLower(strings.TrimSpace(logTypeStrings[level]))
+			fields = append(fields, Field{Key: key, Value: n})
+		}
+	}
+	fields = append(fields,
+		Field{Key: "bytes", Value: st.Bytes},
+		Field{Key: "dropped", Value: st.Dropped},
+		Field{Key: "expired", Value: st.Expired},
+		Field{Key: "suppressed", Value: st.Suppressed},
+		Field{Key: "duration_ms", Value: time.Since(l.stats.since).Milliseconds()},
+	)
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.emit(l.name, time.Time{}, INFO, "logger summary", l.mergeFields(fields), nil)
+	l.mu.Unlock()
+	l.reportErrors()
+}
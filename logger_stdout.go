@@ -1,13 +1,16 @@
 package logger
 
 import (
+	"context"
 	// "bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,7 +37,8 @@ const (
    l.Info("hello")
    l.Warn(1)
 
-   输入格式可以通过 SetLoggerFormat 设置。默认输出格式定义在 见Logger的DefaultLogFormatFunc
+   输出格式可以通过 SetEncoder 设置，默认为带颜色的 TextEncoder，JSON 输出使用 JSONEncoder。
+   也可以通过 SetLoggerFormat 设置格式化函数，设置后优先于编码器。
    可以通过 SetLogLevel 设置输出等级。
 */
 type (
@@ -42,22 +46,97 @@ type (
 	Logger struct {
 		sync.RWMutex
 		mu            sync.Mutex
-		out           io.Writer
-		logFormatFunc FormatFunc
-		logLevel      LogType
-		status        syncStatus  // 日志状态
-		queue         chan string // 通过实现消息队列
-		queueSize     int         // 队列通道大小
+		outMu         sync.Mutex       // 写输出时的互斥锁
+		out           io.Writer        // 主输出
+		fallback      io.Writer        // 主输出写入失败时的备用输出
+		colorMode     ColorMode        // 终端颜色模式
+		outTTY        int32            // 主输出是否为终端
+		closers       []io.Closer      // 由配置创建的输出，关闭日志时关闭
+		sinks         []*Sink          // 附加输出端，经 setSinks 修改
+		logFormatFunc FormatFunc       // 自定义格式化函数，设置后优先于编码器
+		encoder       Encoder          // 编码器
+		logLevel      AtomicLevel      // 日志级别，可与日志调用并发读写
+		status        syncStatus       // 日志状态
+		started       bool             // 是否已启动
+		closed        bool             // 是否已关闭
+		done          chan struct{}    // 关闭信号
+		wg            sync.WaitGroup   // 后台goroutine
+		queue         chan queued      // 通过实现消息队列
+		drainMu       sync.Mutex       // 排空时的互斥锁，保证批次写出顺序
+		drainReq      chan chan error  // 请求消费goroutine排空队列
+		latency       latency          // 写出延迟约定
+		callLatency   histogram        // 日志调用耗时
+		stall         stall            // 写出停滞检查
+		expired       int64            // 过期丢弃的条数
+		ttl           time.Duration    // 派生日志的日志有效期，0 表示不过期
+		errMu         sync.Mutex       // 错误处理函数的互斥锁
+		errorHandler  func(error)      // 错误处理函数
+		lastErrors    []errorRecord    // 最近的内部错误
+		deferred      []error          // 持锁期间暂存的内部错误，由 errMu 保护
+		hasDeferred   int32            // 是否有暂存的内部错误
+		queueSize     int              // 队列通道大小
+		overflow      OverflowPolicy   // 队列满时的处理策略
+		dropped       int64            // 队列满时丢弃的条数
+		onDrop        func(string)     // 丢弃日志时的回调
+		idGenerator   IDGenerator      // ID生成器
+		entryID       bool             // 是否为每条日志附加唯一ID
+		strict        bool             // 严格模式，不支持的类型输出告警
+		reportCaller  bool             // 是否记录调用位置
+		callerSkip    int              // 额外跳过的调用栈层数
+		callerChains  [FATAL + 1]int   // 各级别记录的调用链层数
+		name          string           // 日志名称，用于统计归属
+		source        string           // 日志来源，输出为 source 字段
+		stats         *stats           // 输出统计
+		root          *Logger          // 派生日志的根日志，根日志为nil
+		fields        []Field          // 派生日志携带的字段
+		hooks         []Hook           // 钩子
+		ctxExtractor  ContextExtractor // 从 context 提取字段
+		clock         Clock            // 时间来源
+		errorKinds    []errorKind      // 错误分类规则
+		sampler       *sampler         // 重复日志采样，nil 表示不采样
+		tenants       *tenantState     // 租户策略，nil 表示不按租户区分
+		priorities    *levelPriorities // 按级别的采样优先级，nil 表示不附加
+		separator     Separator        // 主输出的记录分隔方式
+		suppressed    int64            // 采样抑制的条数
+		mem           memoryLimit      // 内存上限
+		summary       bool             // 关闭时输出运行汇总
+		earlyDropped  int64            // 启动前超过上限丢弃的条数
+		locals        localBuffers     // 生产者本地缓冲
+		eventLevel    AtomicLevel      // 业务事件的最低级别
+		eventRoute    EventRoute       // 主输出的业务事件去向
+		viewMu        sync.Mutex       // 状态快照的互斥锁，持有期间不获取其他锁
+		view          struct {         // 状态查询不持有 l.mu 即可读取的快照，见 adminStatus
+			sinks []*Sink     // 同 sinks
+			queue chan queued // 同 queue
+		}
 		// 缓存控制块
 		cache struct {
 			use      bool          // 是否使用缓存
-			data     []string      // 缓存数据
+			data     []queued      // 缓存数据，各条日志的长度与时间
+			buf      *Buffer       // 缓存的日志内容，按顺序连续存放
+			spare    []queued      // 已写出的缓存切片，下次排空时复用，由 drainMu 保护
+			spareBuf *Buffer       // 已写出的缓存缓冲，下次排空时复用，由 drainMu 保护
 			mutex    sync.Mutex    // 写cache时的互斥锁
 			cacheCap int           // 缓存容量默认64
 			duration time.Duration // 同步数据到文件的周期，默认为100毫秒
 		}
 	}
 
+	// 写出停滞检查
+	stall struct {
+		intervals int64 // 允许的最大无写出刷新周期数，0 表示不检查
+		lastFlush int64 // 最近一次完成写出的时间(纳秒)
+	}
+
+	// 队列中的日志
+	queued struct {
+		buf    *Buffer   // 编码后的日志，写出或丢弃后归还缓冲池；缓存中的日志为 nil
+		n      int       // 缓存中的日志在缓存缓冲中的字节数
+		at     time.Time // 入队时间，未开启延迟约定时为零值
+		expire time.Time // 过期时间，写出时已过期则丢弃，零值表示不过期
+		event  bool      // 是否为业务事件，不会被丢弃
+	}
+
 	// log同步的状态
 	syncStatus int
 
@@ -100,8 +179,29 @@ var (
 	// 45;97 底色紫色, 加亮白色;
 	logTypesColors = []string{"45;97", "42;97", "43;97", "43;97", "41;97", "41;97", "41;97"}
 
+	// 预先生成的级别前缀，[0] 不带颜色如 "[ DEBUG    ] "，[1] 带终端颜色
+	levelPrefixes = func() [2][]string {
+		var prefixes [2][]string
+		for index, t := range logTypeStrings {
+			prefixes[0] = append(prefixes[0], "[ "+t+" ] ")
+			prefixes[1] = append(prefixes[1], "[\033["+logTypesColors[index]+"m"+t+"\033[0m] ")
+		}
+		return prefixes
+	}()
+
+	// 预先生成的 DefaultLogFormatFunc 级别与时间格式
+	formatPrefixes = func() []string {
+		prefixes := make([]string, len(logTypesColors))
+		for index, color := range logTypesColors {
+			prefixes[index] = "[\033[" + color + "m%s\033[0m] %s | "
+		}
+		return prefixes
+	}()
+
 	// 声明接口实现者
-	_ ILogger = &Logger{}
+	_ StdLogger  = &Logger{}
+	_ Flusher    = &Logger{}
+	_ Structured = &Logger{}
 )
 
 /*
@@ -110,163 +210,396 @@ var (
 func NewLogger() *Logger {
 	// 实例化日志对象并初始化参数
 	logger := &Logger{}
-
-	// 设置日志的默认参数
-	logger.out = os.Stdout      // 设置输出
-	logger.cache.use = true     // 缓存开关
-	logger.cache.duration = 100 // 缓存同步周期
-	logger.cache.cacheCap = 128 // 缓存容量
-	logger.queueSize = 100000   // 默认队列大小1000000
-	logger.logLevel = DEBUG     // 设置默认级别
-	logger.cache.data = make([]string, 0, logger.cache.cacheCap)
-	logger.logFormatFunc = logger.DefaultLogFormatFunc
+	logger.init()
 
 	return logger
 }
 
+// 设置日志的默认参数
+func (l *Logger) init() {
+	l.setOut(os.Stdout)                     // 设置输出
+	l.cache.use = true                      // 缓存开关
+	l.cache.duration = defaultCacheDuration // 缓存同步周期
+	l.cache.cacheCap = defaultCacheCap      // 缓存容量
+	l.queueSize = defaultQueueSize          // 默认队列大小
+	l.logLevel.SetLevel(DEBUG)              // 设置默认级别
+	l.cache.data = make([]queued, 0, l.cache.cacheCap)
+	l.cache.buf = &Buffer{}
+	l.encoder = &TextEncoder{Color: true}
+	l.stats = newStats()
+	l.clock = systemClock{}
+}
+
+// 是否为 nil 或未经 NewLogger 创建的零值日志，此时所有方法均为空操作
+// 结构体中可选的日志字段无需判空即可调用
+func (l *Logger) noop() bool {
+	return l == nil || l.root == nil && l.stats == nil
+}
+
 // 获取日志类型串
 func GetLogTypeString(t LogType) string {
 	return logTypeStrings[t]
 }
 
-// 启动日志记录器
-func (l *Logger) Start() {
+// 获取预先生成的级别前缀，供自定义编码器复用
+// color 为 false 时如 "[ DEBUG    ] "，为 true 时名称带终端颜色
+func LevelPrefix(t LogType, color bool) string {
+	if color {
+		return levelPrefixes[1][t]
+	}
+	return levelPrefixes[0][t]
+}
+
+// 由名称解析日志类型，忽略大小写及补齐用的空格
+func ParseLogType(s string) (LogType, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for index, t := range logTypeStrings {
+		if strings.TrimSpace(t) == s {
+			return LogType(index), nil
+		}
+	}
+	return DEBUG, fmt.Errorf("logger: unknown log type %q", s)
+}
+
+/*
+ * 启动日志记录器
+ *
+ * 可传入 ctx，取消时写出全部日志并关闭，等同于调用 Close，之后的日志不再输出：
+ *
+ *   g, ctx := errgroup.WithContext(ctx)
+ *   l.Start(ctx)
+ *
+ * 启动前(如包的 init 中)输出的日志不论缓存或队列模式都先留在缓存中，至多 10000 条，
+ * 超出的日志丢弃并计入 Stats().Dropped；Start 时按顺序先写出这些日志，未启动就 Close 时由 Close 写出。
+ */
+func (l *Logger) Start(ctx ...context.Context) {
+	l.start(ctx, l.Close)
+}
+
+// 启动后台goroutine，ctx 取消时调用 close
+func (l *Logger) start(ctx []context.Context, close func() error) {
+	if l.noop() {
+		return
+	}
+
+	// 持锁期间的错误在解锁后上报，错误处理函数可能通过本日志输出
+	defer l.reportErrors()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	// 关闭缓存
-	if !l.cache.use {
-		// 初始化通道
-		l.queue = make(chan string, l.queueSize)
-
-		// 异步写
-		go func() {
-			// 启动监听通道goroutine
-			for {
-				select {
-				case msg, ok := <-l.queue:
-					// 逐个写入终端
-					if ok {
-						_, err := io.WriteString(l.out, msg)
-						if err != nil {
-							// 重试
-							_, err := io.WriteString(l.out, msg)
-							if err != nil {
-								panic(err)
-							}
-						}
+	// 派生日志共用根日志的输出通路
+	if l.started || l.root != nil {
+		return
+	}
+	l.started = true
+
+	// 检查设置，无法运行的设置改用默认值
+	for _, err := range l.checkSettings(true) {
+		l.deferError(err)
+	}
+
+	// 先写出启动前的日志，之后的日志排在其后
+	if err := l.Drain(); err != nil {
+		l.deferError(err)
+	}
+	if l.earlyDropped > 0 {
+		l.deferError(fmt.Errorf("logger: dropped %d entries logged before Start, limit %d", l.earlyDropped, maxEarlyEntries))
+	}
+
+	// 初始化通道，缓存模式与队列模式可在运行时切换，两条通路都需要就绪
+	l.drainMu.Lock()
+	l.queue = make(chan queued, l.queueSize)
+	l.done = make(chan struct{})
+	l.drainReq = make(chan chan error)
+	l.drainMu.Unlock()
+	l.viewMu.Lock()
+	l.view.queue = l.queue
+	l.viewMu.Unlock()
+	l.markFlushed()
+
+	// 异步写
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		// 启动监听通道goroutine
+		for {
+			select {
+			case q, ok := <-l.queue:
+				// 逐个写入终端
+				if ok {
+					buf, at := l.joinQueued([]queued{q})
+					if buf == nil {
+						continue
+					}
+					err := l.write(buf.Bytes())
+					buf.Free()
+					if err == nil && len(l.queue) == 0 {
+						// 队列空闲时写出输出的缓冲
+						err = l.flushOut()
 					}
+					if err != nil {
+						l.handleErrorAsync(err)
+						continue
+					}
+					l.markFlushed()
+					l.observe(at)
 				}
+			case req := <-l.drainReq:
+				// 由本goroutine排空队列，保证写出顺序
+				req <- l.drainQueue()
+			case <-l.done:
+				return
 			}
-		}()
-
-		return
-	}
+		}
+	}()
 
 	// 使用缓存
-	timer := time.NewTicker(time.Millisecond * l.cache.duration)
+	timer := l.clock.NewTicker(time.Millisecond * l.cache.duration)
 
+	l.wg.Add(1)
 	go func() {
+		defer l.wg.Done()
+		defer timer.Stop()
 		// 实现异步写日志
 		for {
 			select {
-			case <-timer.C:
+			case <-timer.Chan():
 				//now := nowFunc()
 				l.RLock()
 				if l.status != statusDoing {
 					// 单开goroutine将当前缓存中的日志刷出
-					go l.flush()
+					l.wg.Add(1)
+					go func() {
+						defer l.wg.Done()
+						l.flush()
+					}()
 				}
 				l.RUnlock()
+			case <-l.done:
+				return
 			}
 		}
 	}()
 
+	// 延迟约定检查
+	l.wg.Add(1)
+	go l.watchdog()
+
+	// ctx 取消时关闭，不计入 wg，避免 Close 等待自身
+	for _, c := range ctx {
+		if c == nil {
+			continue
+		}
+		go func(c context.Context, done chan struct{}) {
+			select {
+			case <-c.Done():
+				if err := close(); err != nil {
+					l.handleError(err)
+				}
+			case <-done:
+			}
+		}(c, l.done)
+	}
+}
+
+// 同步写出缓存和队列中的全部日志
+// 派生日志刷出根日志
+func (l *Logger) Flush() error {
+	if l.noop() {
+		return nil
+	}
+
+	if l.root != nil {
+		return l.root.Flush()
+	}
+
+	l.handoffLocals(true)
+	defer l.reportErrors()
+	return joinErrors(l.Drain(), l.flushSinks())
+}
+
+// 关闭日志：停止后台goroutine并写出缓存和队列中的全部日志，之后的日志调用不再输出
+// 返回的错误合并了主输出和每个附加输出端的失败，一个输出端失败不影响其他输出端的关闭
+// 派生日志的 Close 等同于 Flush，不会关闭根日志
+func (l *Logger) Close() error {
+	if l.noop() {
+		return nil
+	}
+
+	if l.root != nil {
+		return l.root.Flush()
+	}
+
+	// 输出本地缓冲、尚未结束窗口的采样汇总和运行汇总
+	l.handoffLocals(true)
+	l.flushSamples(true)
+	l.logSummary()
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	started := l.started
+	l.mu.Unlock()
+
+	// 等待后台goroutine退出后再排空，保证写出顺序
+	if started {
+		close(l.done)
+		l.wg.Wait()
+	}
+
+	// 主输出、各输出端与创建的文件的错误全部返回，可用 errors.Is 判断
+	defer l.reportErrors()
+	return joinErrors(l.Drain(), l.closeSinks(), l.closeClosers())
 }
 
 // 设置cache开关
+// 运行中切换时先排空原通路中的日志，避免日志滞留在缓存或队列里
 func (l *Logger) SetCacheSwitch(use bool) {
+	if l.noop() {
+		return
+	}
+
+	defer l.reportErrors()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cache.use == use {
+		return
+	}
+	if l.started {
+		if err := l.Drain(); err != nil {
+			l.deferError(err)
+		}
+	}
 	l.cache.use = use
 }
 
+// 设置输出
+func (l *Logger) SetOutput(w io.Writer) {
+	if l.noop() {
+		return
+	}
+
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+	l.setOut(w)
+}
+
 // 设置cache周期
 func (l *Logger) SetCacheDuration(duration time.Duration) {
+	if l.noop() {
+		return
+	}
+
 	l.cache.duration = duration
 }
 
 // 设置队列容量
 func (l *Logger) SetQueueSize(size int) {
+	if l.noop() {
+		return
+	}
+
 	l.queueSize = size
 }
 
 // 设置cache容量
 func (l *Logger) SetCacheCap(cap int) {
+	if l.noop() {
+		return
+	}
+
 	l.cache.cacheCap = cap
 }
 
-// 设置日志级别
+// 设置日志级别，可在输出日志的同时调用
 func (l *Logger) SetLogLevel(logType LogType) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.logLevel = logType
+	if l.noop() {
+		return
+	}
+
+	l.logLevel.SetLevel(logType)
 }
 
 // 获取日志级别
 func (l *Logger) GetLogLevel() LogType {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	return l.logLevel
+	if l.noop() {
+		return DEBUG
+	}
+
+	return l.logLevel.Level()
 }
 
 // 设置格式化log输出函数
 // 函数返回 format 和 对应格式 []interface{}
 func (l *Logger) SetLoggerFormat(formatFunc FormatFunc) {
+	if l.noop() {
+		return
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.logFormatFunc = formatFunc
 }
 
+// 设置编码器，同时清除 SetLoggerFormat 设置的格式化函数
+func (l *Logger) SetEncoder(enc Encoder) {
+	if l.noop() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.encoder = enc
+	l.logFormatFunc = nil
+}
+
 // 输出信息
-func (l *Logger) Debug(i interface{}) {
-	l.log(DEBUG, i)
+// 单个参数时 string 为文本、[]string 为数据列；多个参数时按 fmt.Sprint 拼接为文本，与标准库 log.Print 一致
+func (l *Logger) Debug(args ...interface{}) {
+	l.log(DEBUG, message(args))
 }
 
-func (l *Logger) Info(i interface{}) {
-	l.log(INFO, i)
+func (l *Logger) Info(args ...interface{}) {
+	l.log(INFO, message(args))
 }
 
-func (l *Logger) Notice(i interface{}) {
-	l.log(NOTICE, i)
+func (l *Logger) Notice(args ...interface{}) {
+	l.log(NOTICE, message(args))
 }
 
-func (l *Logger) Warn(i interface{}) {
-	l.log(WARN, i)
+func (l *Logger) Warn(args ...interface{}) {
+	l.log(WARN, message(args))
 }
 
-func (l *Logger) Error(i interface{}) {
-	l.log(ERROR, i)
+func (l *Logger) Error(args ...interface{}) {
+	l.log(ERROR, message(args))
 }
 
-func (l *Logger) Critical(i interface{}) {
-	l.log(CRITICAL, i)
+func (l *Logger) Critical(args ...interface{}) {
+	l.log(CRITICAL, message(args))
 }
 
-func (l *Logger) Fatal(i interface{}) {
-	l.log(FATAL, i)
+func (l *Logger) Fatal(args ...interface{}) {
+	l.log(FATAL, message(args))
 }
 
-func (l *Logger) DefaultLogFormatFunc(logType LogType, i interface{}) (string, []interface{}, bool) {
-	// 异常捕获
+func (l *Logger) DefaultLogFormatFunc(logType LogType, i interface{}) (_ string, _ []interface{}, isLog bool) {
+	// 异常捕获，上报后丢弃该条日志
 	defer func() {
 		e := recover()
 		if e != nil {
-			panic(debug.Stack())
+			l.deferError(fmt.Errorf("logger: format panic: %v\n%s", e, debug.Stack()))
+			isLog = false
 		}
 	}()
 
 	// 计算日期format
 	layout := "2006/01/02 - 15:04:05.0000"
-	formatTime := time.Now().Format(layout)
+	formatTime := l.now().Format(layout)
 	if len(formatTime) != len(layout) {
 		// 可能出现结尾是0被省略如：2006/01/02 - 15:04:05.9 补足成 2006/01/02 - 15:04:05.9000
 		if len(formatTime) == 21 {
@@ -275,6 +608,11 @@ func (l *Logger) DefaultLogFormatFunc(logType LogType, i interface{}) (string, [
 		formatTime += ".000"[4-(len(layout)-len(formatTime)) : 4]
 	}
 
+	// 附加日志条目ID
+	if id := l.newEntryID(); id != "" {
+		formatTime += " | " + id
+	}
+
 	// 计算数据format
 	// format := ""
 	values := []interface{}{}
@@ -283,9 +621,7 @@ func (l *Logger) DefaultLogFormatFunc(logType LogType, i interface{}) (string, [
 	if iSli, ok := i.([]string); ok {
 		// 切片
 		l := len(iSli)
-		b.WriteString("[\033[")
-		b.WriteString(logTypesColors[logType])
-		b.WriteString("m%s\033[0m] %s | ")
+		b.WriteString(formatPrefixes[logType])
 		// format = "[\033[" + logTypesColors[logType] + "m%s\033[0m] %s | "
 		values = make([]interface{}, l+2)
 		values[0] = logTypeStrings[logType]
@@ -320,9 +656,8 @@ func (l *Logger) DefaultLogFormatFunc(logType LogType, i interface{}) (string, [
 		// format += "\n"
 	} else if iStr, ok := i.(string); ok {
 		// 文本
-		b.WriteString("[\033[")
-		b.WriteString(logTypesColors[logType])
-		b.WriteString("m%s\033[0m] %s | %s | \n")
+		b.WriteString(formatPrefixes[logType])
+		b.WriteString("%s | \n")
 		// format = "[\033[" + logTypesColors[logType] + "m%s\033[0m] %s | %s | \n"
 		// 计算输出值
 		values = make([]interface{}, 3)
@@ -335,65 +670,554 @@ func (l *Logger) DefaultLogFormatFunc(logType LogType, i interface{}) (string, [
 	return b.String(), values, true
 }
 
-func (l *Logger) log(logType LogType, i interface{}) {
+func (l *Logger) log(logType LogType, i interface{}, fields ...Field) LogResult {
+	return l.logAt(0, logType, i, fields)
+}
+
+// 输出一条日志，pc 为调用位置(如 slog.Record.PC)，为 0 时按调用栈获取日志方法的调用方
+// 日志方法须直接调用 log 以保证调用栈层数一致
+// 返回日志的处理结果
+func (l *Logger) logAt(pc uintptr, logType LogType, i interface{}, fields []Field) LogResult {
+	if l.noop() {
+		return LogClosed
+	}
+
+	// 统计调用耗时
+	if h := l.callHistogram(); atomic.LoadInt32(&h.enabled) == 1 {
+		defer h.observe(time.Now())
+	}
+
+	if l.logLevel.Level() > logType {
+		return LogFiltered
+	}
 	l.mu.Lock()
-	defer l.mu.Unlock()
 
-	if l.logLevel > logType {
-		return
+	// 通过级别过滤后才格式化
+	switch m := i.(type) {
+	case sprintArgs:
+		i = fmt.Sprint(m...)
+	case sprintfArgs:
+		i = fmt.Sprintf(m.format, m.args...)
 	}
 
-	format, data, isLog := l.logFormatFunc(logType, i)
-	if !isLog {
-		return
+	// 调用位置: logAt <- log <- Info 等日志方法 <- 调用方
+	var caller *Caller
+	if l.reportCaller {
+		if pc == 0 {
+			pc = callerPC(3 + l.callerSkip)
+		}
+		caller = newCaller(pc)
+	}
+	if logType >= DEBUG && logType <= FATAL && l.callerChains[logType] > 0 {
+		chain := callerChain(3+l.callerSkip, pc, l.callerChains[logType])
+		fields = append(fields[:len(fields):len(fields)], Field{Key: CallerChainKey, Value: chain})
+	}
+
+	// 合并来源与派生日志携带的字段
+	fields = l.mergeFields(fields)
+	name, ttl := l.name, l.ttl
+
+	// 派生日志交给根日志输出
+	root := l
+	if l.root != nil {
+		l.mu.Unlock()
+		root = l.root
+		root.mu.Lock()
 	}
 
-	var err error
-	if l.cache.use {
-		// 使用缓存
-		l.cache.data = append(l.cache.data, fmt.Sprintf(string(format), data...))
+	// 已关闭的日志不再输出
+	if root.closed {
+		root.mu.Unlock()
+		return LogClosed
+	}
+	expire := time.Time{}
+	if ttl > 0 {
+		expire = root.now().Add(ttl)
+	}
+
+	// 租户策略
+	if root.tenants != nil {
+		var result LogResult
+		if fields, result = root.tenants.apply(logType, fields); result != LogAccepted {
+			if result == LogSampled {
+				atomic.AddInt64(&root.suppressed, 1)
+			}
+			root.mu.Unlock()
+			return result
+		}
+	}
+
+	// 按级别的采样优先级
+	if root.priorities != nil {
+		fields = root.priorities.apply(logType, fields)
+	}
+
+	// 重复日志采样
+	if root.sampler != nil {
+		keep, summary := root.sampler.sample(logType, i, fields, time.Now())
+		if summary != nil {
+			root.emitSummary(summary)
+		}
+		if !keep {
+			atomic.AddInt64(&root.suppressed, 1)
+			root.mu.Unlock()
+			root.reportErrors()
+			return LogSampled
+		}
+	}
+
+	// 错误分类
+	if len(root.errorKinds) > 0 {
+		fields = root.classifyErrors(fields)
+	}
+
+	e, result := root.emit(name, expire, logType, i, fields, caller)
+	hooks := root.hooks
+	root.mu.Unlock()
+	root.reportErrors()
+
+	// 钩子在锁外执行，慢的钩子不会阻塞其他日志调用
+	if e != nil && len(hooks) > 0 {
+		root.fireHooks(hooks, e)
+	}
+	return result
+}
+
+// 格式化并输出一条日志，返回生成的日志条目(未输出时为 nil)和处理结果。调用方需持有 l.mu
+func (l *Logger) emit(name string, expire time.Time, logType LogType, i interface{}, fields []Field, caller *Caller) (*Entry, LogResult) {
+	ev, event := i.(eventMsg)
+	if event {
+		i = string(ev)
+	}
+
+	// 自定义格式化函数
+	if l.logFormatFunc != nil {
+		switch v := i.(type) {
+		case *AccessEntry:
+			i = v.columns()
+		case *DiffEntry:
+			i = v.columns()
+		}
+		format, data, isLog := l.logFormatFunc(logType, i)
+		if !isLog {
+			return nil, LogFiltered
+		}
+		msg := fmt.Sprintf(string(format), data...)
+		if l.colorOff() {
+			msg = stripColor(msg)
+		}
+		if msg == "" {
+			// 不支持的类型格式化为空
+			l.unsupported(name, expire, i, caller)
+			return nil, LogInvalid
+		}
+		buf := GetBuffer()
+		buf.WriteString(msg)
+		l.fanout(logType, nil, buf.Bytes(), expire, event)
+		result := l.output(name, logType, buf, expire, event)
+		if len(l.hooks) == 0 {
+			return nil, result
+		}
+		// 供钩子使用的条目，内容为格式化结果
+		return &Entry{Level: logType, Time: l.now(), Caller: caller, Message: strings.TrimRight(msg, "\n"), Fields: fields}, result
+	}
+
+	// 生成日志条目并编码，无钩子时条目用完即归还
+	e := getEntry()
+	e.Level, e.Time, e.ID, e.Caller, e.Fields = logType, l.now(), l.newEntryID(), caller, fields
+	if len(l.hooks) == 0 {
+		defer putEntry(e)
+	}
+	if iSli, ok := i.([]string); ok {
+		e.Columns = iSli
+	} else if iStr, ok := i.(string); ok {
+		e.Message = iStr
+	} else if a, ok := i.(*AccessEntry); ok {
+		// 文本按数据列输出，JSON 按字段输出
+		e.Access, e.Columns = a, a.columns()
+	} else if d, ok := i.(*DiffEntry); ok {
+		e.Diff, e.Columns = d, d.columns()
 	} else {
-		// 追加进队列
-		l.queue <- fmt.Sprintf(string(format), data...)
-		// _, err = fmt.Fprintf(l.out, string(format), data...)
+		l.unsupported(name, expire, i, caller)
+		return nil, LogInvalid
 	}
-	if err != nil {
-		panic(err)
+	off := l.colorOff()
+	enc := l.colorEncoder(l.encoder, off)
+	buf := GetBuffer()
+	if err := encodeTo(enc, buf, e); err != nil {
+		buf.Free()
+		l.deferError(err)
+		return nil, LogInvalid
+	}
+	if off && enc == l.encoder {
+		// 自定义编码器输出的颜色
+		if s := buf.String(); strings.IndexByte(s, '\033') >= 0 {
+			buf.Reset()
+			buf.WriteString(stripColor(s))
+		}
 	}
+	// 交给缓存或队列后缓冲可能随时被写出归还，先分发到附加输出端
+	l.fanout(logType, e, buf.Bytes(), expire, event)
+	result := l.output(name, logType, buf, expire, event)
+	if len(l.hooks) == 0 {
+		return nil, result
+	}
+	return e, result
+}
+
+// 将编码后的日志交给缓存或队列，buf 交由输出通路归还。event 为业务事件，不会被丢弃
+func (l *Logger) output(name string, level LogType, buf *Buffer, expire time.Time, event bool) LogResult {
+	if !l.eventRoute.accepts(event) {
+		buf.Free()
+		return LogFiltered
+	}
+	l.separator.apply(buf)
+	if l.stats != nil {
+		l.stats.add(name, level, buf.Len())
+	}
+
+	if l.checkMemory(buf.Len()) {
+		return l.writeSync(buf)
+	}
+
+	q := queued{buf: buf, at: l.enqueueTime(), expire: expire, event: event}
+	if l.cache.use || !l.started {
+		// 使用缓存，内容追加到缓存缓冲后归还；启动前的日志不论模式都先留在缓存中
+		q.buf, q.n = nil, buf.Len()
+		l.cache.mutex.Lock()
+		if !l.started && !event && len(l.cache.data) >= maxEarlyEntries {
+			l.cache.mutex.Unlock()
+			buf.Free()
+			atomic.AddInt64(&l.dropped, 1)
+			l.earlyDropped++
+			return LogDropped
+		}
+		l.cache.buf.Write(buf.Bytes())
+		l.cache.data = append(l.cache.data, q)
+		l.cache.mutex.Unlock()
+		buf.Free()
+	} else {
+		atomic.AddInt64(&l.mem.queued, int64(buf.Len()))
+		if event {
+			l.enqueueEvent(q)
+		} else if !l.enqueue(q) {
+			// 队列满被丢弃
+			return LogDropped
+		}
+	}
+	return LogAccepted
+}
+
+// 输出本地缓冲交接的一批日志，buf 交由输出通路归还
+func (l *Logger) outputBatch(buf *Buffer, entries int) LogResult {
+	defer l.reportErrors()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		buf.Free()
+		atomic.AddInt64(&l.dropped, int64(entries))
+		return LogClosed
+	}
+	if l.checkMemory(buf.Len()) {
+		return l.writeSync(buf)
+	}
+
+	q := queued{buf: buf, at: l.enqueueTime()}
+	if l.cache.use || !l.started {
+		q.buf, q.n = nil, buf.Len()
+		l.cache.mutex.Lock()
+		l.cache.buf.Write(buf.Bytes())
+		l.cache.data = append(l.cache.data, q)
+		l.cache.mutex.Unlock()
+		buf.Free()
+		return LogAccepted
+	}
+	atomic.AddInt64(&l.mem.queued, int64(buf.Len()))
+	if !l.enqueue(q) {
+		// 整批被丢弃，enqueue 已计一条
+		atomic.AddInt64(&l.dropped, int64(entries-1))
+		return LogDropped
+	}
+	return LogAccepted
 }
 
 // 将当前缓存中的日志刷出
 func (l *Logger) flush() error {
+	l.Lock()
 	l.status = statusDoing
+	l.Unlock()
 	defer func() {
+		l.Lock()
 		l.status = statusDone
+		l.Unlock()
 	}()
 
-	// 获取缓存数据
+	l.handoffLocals(false)
+	err := l.Drain()
+	if err != nil {
+		l.handleError(err)
+	}
+	l.reportErrors()
+
+	return err
+}
+
+// 同步排空缓存和队列中尚未写出的日志
+// 缓存模式的定时刷新与模式切换共用此方法
+func (l *Logger) Drain() (err error) {
+	if l.noop() {
+		return nil
+	}
+
+	l.drainMu.Lock()
+	defer l.drainMu.Unlock()
+	defer func() {
+		if ferr := l.flushOut(); err == nil {
+			err = ferr
+		}
+		if err == nil {
+			l.markFlushed()
+		}
+	}()
+
+	// 获取缓存数据，与上次排空的切片和缓冲交替使用
+	if l.cache.spareBuf == nil {
+		l.cache.spare = make([]queued, 0, l.cache.cacheCap)
+		l.cache.spareBuf = &Buffer{}
+	}
 	l.cache.mutex.Lock()
-	cache := l.cache.data
-	l.cache.data = l.cache.data[0:0] // 极大的节省空间分配减轻垃圾回收压力
-	// l.cache.data = make([]string, 0, l.cache.cacheCap)
+	pending, pendingBuf := l.cache.data, l.cache.buf
+	l.cache.data, l.cache.buf = l.cache.spare[:0], l.cache.spareBuf
 	l.cache.mutex.Unlock()
 
-	if len(cache) == 0 {
+	p, at := l.joinCached(pendingBuf, pending)
+	if len(p) > 0 {
+		err = l.write(p)
+	}
+	if cap(pendingBuf.b) > maxCacheBufferSize {
+		// 突发大量日志后释放过大的缓冲
+		pendingBuf.b = nil
+	}
+	pendingBuf.Reset()
+	l.cache.spare, l.cache.spareBuf = pending[:0], pendingBuf
+	if err != nil {
+		return err
+	}
+	if len(p) > 0 {
+		l.observe(at)
+	}
+
+	// 运行中交给消费goroutine排空队列，避免与其正在写出的日志乱序
+	if l.drainReq != nil {
+		req := make(chan error, 1)
+		select {
+		case l.drainReq <- req:
+			return <-req
+		case <-l.done:
+		}
+	}
+
+	return l.drainQueue()
+}
+
+// 取出队列中尚未消费的日志并写出
+func (l *Logger) drainQueue() error {
+	pending := []queued{}
+loop:
+	for {
+		select {
+		case q, ok := <-l.queue:
+			if !ok {
+				break loop
+			}
+			pending = append(pending, q)
+		default:
+			break loop
+		}
+	}
+
+	buf, at := l.joinQueued(pending)
+	if buf == nil {
 		return nil
 	}
+	err := l.write(buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	l.observe(at)
+	return nil
+}
 
-	_, err := io.WriteString(l.out, strings.Join(cache, ""))
+// 跳过缓存中已过期的日志，返回待写内容与最早一条的入队时间。items 为各条日志在 buf 中的长度
+func (l *Logger) joinCached(buf *Buffer, items []queued) ([]byte, time.Time) {
+	if len(items) == 0 {
+		return nil, time.Time{}
+	}
+
+	expiring := false
+	for _, q := range items {
+		if !q.expire.IsZero() {
+			expiring = true
+			break
+		}
+	}
+	if !expiring {
+		return buf.Bytes(), items[0].at
+	}
+
+	// 原地移除过期的日志
+	now := l.clock.Now()
+	oldest := time.Time{}
+	expired := 0
+	w, r := 0, 0
+	for _, q := range items {
+		seg := buf.b[r : r+q.n]
+		r += q.n
+		if !q.expire.IsZero() && now.After(q.expire) {
+			expired++
+			continue
+		}
+		if oldest.IsZero() {
+			oldest = q.at
+		}
+		w += copy(buf.b[w:], seg)
+	}
+	if expired > 0 {
+		atomic.AddInt64(&l.expired, int64(expired))
+	}
+	return buf.b[:w], oldest
+}
+
+// 拼接待写日志并跳过已过期的日志，返回拼接结果与最早一条的入队时间
+func (l *Logger) joinQueued(items []queued) (*Buffer, time.Time) {
+	atomic.AddInt64(&l.mem.queued, -queuedBytes(items))
+	buf, at, expired := joinQueued(items, l.clock)
+	if expired > 0 {
+		atomic.AddInt64(&l.expired, int64(expired))
+	}
+	return buf, at
+}
+
+/*
+ * 拼接待写日志，返回拼接结果、最早一条的入队时间与过期条数，是否过期按 clock 判断
+ *
+ * 各条日志的缓冲在拼接后归还，items 中的缓冲置为 nil；拼接结果由调用方写出后归还，无日志时为 nil。
+ */
+func joinQueued(items []queued, clock Clock) (*Buffer, time.Time, int) {
+	if len(items) == 1 && items[0].expire.IsZero() {
+		buf := items[0].buf
+		items[0].buf = nil
+		return buf, items[0].at, 0
+	}
+
+	var b *Buffer
+	now := time.Time{}
+	oldest := time.Time{}
+	expired := 0
+	for i, q := range items {
+		items[i].buf = nil
+		if !q.expire.IsZero() {
+			if now.IsZero() {
+				now = clock.Now()
+			}
+			if now.After(q.expire) {
+				expired++
+				q.buf.Free()
+				continue
+			}
+		}
+		if b == nil {
+			b = GetBuffer()
+			oldest = q.at
+		}
+		b.Write(q.buf.Bytes())
+		q.buf.Free()
+	}
+	return b, oldest, expired
+}
+
+// 写入输出，失败时重试一次，仍失败时写入备用输出并返回主输出的错误
+func (l *Logger) write(p []byte) error {
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
+
+	_, err := l.out.Write(p)
+	if err != nil && outputClosed(l.out, err) {
+		// 输出已关闭，不再重试，停用后改写备用输出
+		err = fmt.Errorf("%w: %s: %v, disabled", ErrOutputClosed, writerName(l.out), err)
+		l.disableOut()
+		if _, ferr := l.out.Write(p); ferr != nil {
+			err = errors.Join(err, fmt.Errorf("logger: write fallback: %w", ferr))
+		}
+		return err
+	}
 	if err != nil {
 		// 重试
-		_, err := io.WriteString(l.out, strings.Join(cache, ""))
-		if err != nil {
-			panic(err)
+		_, err = l.out.Write(p)
+	}
+	if err != nil {
+		err = fmt.Errorf("logger: write output: %w", err)
+		if l.fallback != nil {
+			if _, ferr := l.fallback.Write(p); ferr != nil {
+				err = errors.Join(err, fmt.Errorf("logger: write fallback: %w", ferr))
+			}
 		}
 	}
+	return err
+}
+
+// 带写入缓冲的输出，见 WithBufferSize
+type bufferedWriter interface {
+	io.Writer
+	flushBuffer() error
+}
+
+// 写出主输出的缓冲
+func (l *Logger) flushOut() error {
+	l.outMu.Lock()
+	defer l.outMu.Unlock()
 
+	if bw, ok := l.out.(bufferedWriter); ok {
+		if err := bw.flushBuffer(); err != nil {
+			return fmt.Errorf("logger: flush output: %w", err)
+		}
+	}
 	return nil
 }
 
-// 兼容gorm日志实现Print
-func (l *Logger) Print(v interface{}) {
-	// @Todo...
-	panic("method not implement")
+/*
+ * 兼容gorm日志实现Print
+ *
+ *   db.SetLogger(l) // gorm v1
+ *
+ * gorm v1 的 SQL 日志以 INFO 级别输出，SQL、参数、影响行数、耗时与位置作为字段；
+ * 错误日志以 ERROR 级别输出；其他调用按 fmt.Sprint 拼接输出。gorm v2 见 contrib/gormlogger 包。
+ */
+func (l *Logger) Print(v ...interface{}) {
+	if len(v) == 0 {
+		return
+	}
+
+	switch v[0] {
+	case "sql":
+		// "sql", 位置, 耗时, SQL, 参数, 影响行数
+		if len(v) >= 6 {
+			l.log(INFO, "sql",
+				Field{Key: "sql", Value: v[3]},
+				Field{Key: "vars", Value: v[4]},
+				Field{Key: "rows", Value: v[5]},
+				Field{Key: "elapsed", Value: fmt.Sprint(v[2])},
+				Field{Key: "source", Value: v[1]})
+			return
+		}
+	case "log":
+		// "log", 位置, 消息...
+		if len(v) >= 3 {
+			l.log(ERROR, fmt.Sprint(v[2:]...), Field{Key: "source", Value: v[1]})
+			return
+		}
+	}
+	l.log(INFO, sprintArgs(v))
 }
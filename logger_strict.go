@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+)
+
+// 设置严格模式：日志内容无法输出(如 SetLoggerFormat 的格式化函数不支持该类型)时，不再静默丢弃，
+// 而是输出一条 WARN 日志说明类型和调用位置，便于在开发阶段发现误用
+func (l *Logger) SetStrict(strict bool) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetStrict(strict)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.strict = strict
+}
+
+// 处理不支持类型的日志内容，严格模式下输出告警。调用方需持有 l.mu
+func (l *Logger) unsupported(name string, expire time.Time, i interface{}, caller *Caller) {
+	if !l.strict {
+		return
+	}
+	msg := fmt.Sprintf("logger: dropped entry with unsupported type %T", i)
+	if caller != nil {
+		msg += " at " + caller.String()
+	}
+	l.emit(name, expire, WARN, msg, nil, caller)
+}
@@ -0,0 +1,306 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 汇总中保留的模板/错误条数
+const summaryTopN = 10
+
+var (
+	// 模板归一化时替换的数字片段
+	templateDigits = regexp.MustCompile(`[0-9]+(\.[0-9]+)?`)
+)
+
+// 按小时汇总的日志统计
+type Summary struct {
+	Hour      time.Time      `json:"hour"`       // 所属小时
+	Total     int            `json:"total"`      // 日志总数
+	Levels    map[string]int `json:"levels"`     // 按级别计数
+	Templates []SummaryItem  `json:"templates"`  // 出现最多的模板
+	TopErrors []SummaryItem  `json:"top_errors"` // 出现最多的错误(ERROR及以上)模板
+}
+
+// 模板计数
+type SummaryItem struct {
+	Template string `json:"template"`
+	Count    int    `json:"count"`
+}
+
+// 汇总时存在无法解析的行，见 Summarize
+var ErrUnparsedLines = errors.New("logger: unparsed lines")
+
+/*
+ * 读取日志文件生成按小时的汇总，按时间升序返回
+ * .gz 文件解压后读取。空行与切换标记行(RotateMarker)忽略，其他无法解析的行不计入汇总，
+ * 此时仍返回其余记录的汇总，同时返回各文件无法解析的行数，可用 errors.Is(err, ErrUnparsedLines) 判断
+ */
+func Summarize(files ...string) ([]*Summary, error) {
+	hours := summaryHours{}
+	var errs []error
+	for _, name := range files {
+		h, skipped, err := summarizeFile(name)
+		if err != nil {
+			return nil, err
+		}
+		hours.merge(h)
+		if skipped > 0 {
+			errs = append(errs, fmt.Errorf("%w: %d in %s", ErrUnparsedLines, skipped, name))
+		}
+	}
+	return hours.summaries(), joinErrors(errs...)
+}
+
+// 一小时的计数
+type summaryCounter struct {
+	summary   *Summary
+	templates map[string]int
+	errors    map[string]int
+}
+
+// 按小时的计数
+type summaryHours map[time.Time]*summaryCounter
+
+// 汇总一个文件，返回无法解析的行数
+func summarizeFile(name string) (summaryHours, int, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, 0, fmt.Errorf("logger: read %s: %w", name, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	hours := summaryHours{}
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, RotateMarker) {
+			continue
+		}
+		record, ok := ParseRecord(line)
+		if !ok || record.Time.IsZero() {
+			skipped++
+			continue
+		}
+		hours.add(record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("logger: read %s: %w", name, err)
+	}
+	return hours, skipped, nil
+}
+
+// 计入一条记录
+func (h summaryHours) add(r Record) {
+	t := r.Time
+	hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	c, ok := h[hour]
+	if !ok {
+		c = &summaryCounter{
+			summary:   &Summary{Hour: hour, Levels: map[string]int{}},
+			templates: map[string]int{},
+			errors:    map[string]int{},
+		}
+		h[hour] = c
+	}
+
+	template := Template(strings.Join(r.Columns, " | "))
+	c.summary.Total++
+	c.summary.Levels[strings.TrimSpace(logTypeStrings[r.Level])]++
+	c.templates[template]++
+	if r.Level >= ERROR {
+		c.errors[template]++
+	}
+}
+
+// 合并另一组计数
+func (h summaryHours) merge(o summaryHours) {
+	for hour, oc := range o {
+		c, ok := h[hour]
+		if !ok {
+			h[hour] = oc
+			continue
+		}
+		c.summary.Total += oc.summary.Total
+		for k, n := range oc.summary.Levels {
+			c.summary.Levels[k] += n
+		}
+		for k, n := range oc.templates {
+			c.templates[k] += n
+		}
+		for k, n := range oc.errors {
+			c.errors[k] += n
+		}
+	}
+}
+
+// 按时间升序的汇总
+func (h summaryHours) summaries() []*Summary {
+	summaries := make([]*Summary, 0, len(h))
+	for _, c := range h {
+		c.summary.Templates = topItems(c.templates, summaryTopN)
+		c.summary.TopErrors = topItems(c.errors, summaryTopN)
+		summaries = append(summaries, c.summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Hour.Before(summaries[j].Hour)
+	})
+	return summaries
+}
+
+// 日志内容归一化为模板，数字片段替换为"#"
+func Template(msg string) string {
+	return templateDigits.ReplaceAllString(msg, "#")
+}
+
+// 以JSON行写出汇总
+func WriteSummaries(w io.Writer, summaries []*Summary) error {
+	enc := json.NewEncoder(w)
+	for _, s := range summaries {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+ * 压缩归档：汇总匹配 pattern 的轮转文件并追加写入 summaryPath
+ * remove 为 true 时在汇总写入成功后删除原始文件，便于长期保留的主机提前清理
+ *
+ * exclude 中的文件(如正在写入的当前文件)不处理，回滚文件可直接使用 RotateFileLogger.CompactFiles。
+ * 含无法解析的行或读取失败的文件不计入汇总也不删除，其错误在处理完其他文件后一并返回
+ */
+func CompactFiles(pattern string, summaryPath string, remove bool, exclude ...string) error {
+	names, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	skip := map[string]bool{filepath.Clean(summaryPath): true}
+	for _, name := range exclude {
+		skip[filepath.Clean(name)] = true
+	}
+	files := make([]string, 0, len(names))
+	for _, name := range names {
+		if !skip[filepath.Clean(name)] {
+			files = append(files, name)
+		}
+	}
+	return compactFiles(files, summaryPath, remove)
+}
+
+/*
+ * 压缩归档本日志的旧文件，当前写入的文件与目录中其他程序的日志不处理，其他同 CompactFiles
+ */
+func (l *RotateFileLogger) CompactFiles(summaryPath string, remove bool) error {
+	l.outMu.Lock()
+	active := l.filePath
+	pattern := l.backupPattern()
+	l.outMu.Unlock()
+
+	dir := l.dirPath
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == filepath.Base(active) || !pattern.MatchString(name) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if filepath.Clean(path) != filepath.Clean(summaryPath) {
+			files = append(files, path)
+		}
+	}
+	return compactFiles(files, summaryPath, remove)
+}
+
+// 汇总完整解析的文件并追加写入 summaryPath，remove 时只删除这些文件
+func compactFiles(files []string, summaryPath string, remove bool) error {
+	hours := summaryHours{}
+	done := make([]string, 0, len(files))
+	var errs []error
+	for _, name := range files {
+		h, skipped, err := summarizeFile(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if skipped > 0 {
+			errs = append(errs, fmt.Errorf("%w: %d in %s", ErrUnparsedLines, skipped, name))
+			continue
+		}
+		hours.merge(h)
+		done = append(done, name)
+	}
+	if len(done) == 0 {
+		return joinErrors(errs...)
+	}
+
+	out, err := os.OpenFile(summaryPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	err = WriteSummaries(out, hours.summaries())
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if remove {
+		for _, name := range done {
+			if err := os.Remove(name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return joinErrors(errs...)
+}
+
+// 取计数最多的前n项
+func topItems(counts map[string]int, n int) []SummaryItem {
+	items := make([]SummaryItem, 0, len(counts))
+	for template, count := range counts {
+		items = append(items, SummaryItem{Template: template, Count: count})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Template < items[j].Template
+	})
+	if len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
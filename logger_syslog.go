@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// syslog 默认的设施 user
+const defaultSyslogFacility = 1
+
+// syslog 输出配置
+type SyslogConfig struct {
+	Network  string // udp 或 tcp，为空时连接本机 syslog(/dev/log 等)
+	Addr     string // 远端地址，如 "logs.example.com:514"
+	Facility int    // 设施，0 使用 user(1)，如 local0 为 16
+	Tag      string // APP-NAME，为空使用程序名
+	Hostname string // HOSTNAME，为空使用本机名
+
+	Severities *SeverityProfile // 级别到严重性的映射，为 nil 使用 SyslogSeverities
+}
+
+/*
+ * 创建 syslog 输出，每条日志按 RFC 5424 格式发送，日志级别映射为 syslog 严重性
+ *
+ *   w := logger.NewSyslogSink(logger.SyslogConfig{Network: "udp", Addr: "logs:514", Tag: "api"})
+ *   l.AddOutput(w, logger.WARN)
+ *   defer w.Close()
+ *
+ * 附加字段以 key=value 跟在消息之后。TCP 与本机流式连接以换行分隔消息。
+ * 连接与重连同 NewNetSink。
+ */
+func NewSyslogSink(cfg SyslogConfig) *SyslogSink {
+	if cfg.Facility == 0 {
+		cfg.Facility = defaultSyslogFacility
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = filepath.Base(os.Args[0])
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+
+	if cfg.Severities == nil {
+		cfg.Severities = &SyslogSeverities
+	}
+
+	s := &SyslogSink{
+		facility:   cfg.Facility,
+		severities: *cfg.Severities,
+		header:     " " + syslogHeaderField(cfg.Hostname) + " " + syslogHeaderField(cfg.Tag) + " " + strconv.Itoa(os.Getpid()) + " - - ",
+	}
+	if cfg.Network == "" {
+		// 本机 syslog，依次尝试常见的套接字路径
+		s.conn.name = "local syslog"
+		s.conn.dial = dialLocalSyslog
+		return s
+	}
+	s.conn.name = cfg.Network + "://" + cfg.Addr
+	s.conn.dial = func() (net.Conn, error) {
+		return net.DialTimeout(cfg.Network, cfg.Addr, netDialTimeout)
+	}
+	return s
+}
+
+// syslog 输出，同时是 RFC 5424 编码器，AddOutput 时作为输出端的编码器
+type SyslogSink struct {
+	facility   int
+	severities SeverityProfile
+	header     string // 时间之后的 HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA
+	conn       netConn
+}
+
+// 声明接口实现者
+var _ BufferEncoder = &SyslogSink{}
+
+func (s *SyslogSink) Encode(e *Entry) ([]byte, error) {
+	buf := GetBuffer()
+	defer buf.Free()
+	if err := s.EncodeTo(buf, e); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (s *SyslogSink) EncodeTo(b *Buffer, e *Entry) error {
+	b.WriteByte('<')
+	b.AppendInt(int64(s.facility*8 + s.severities.Number(e.Level)))
+	b.WriteString(">1 ")
+	b.AppendTime(e.Time, "2006-01-02T15:04:05.000000Z07:00")
+	b.WriteString(s.header)
+
+	// 消息中的换行会拆开消息，替换为空格
+	start := b.Len()
+	if e.Columns != nil {
+		for j, col := range e.Columns {
+			if j > 0 {
+				b.WriteString(" | ")
+			}
+			b.WriteString(trimColorSuffix(col))
+		}
+	} else {
+		b.WriteString(e.Message)
+	}
+	for _, f := range e.Fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		b.AppendValue(f.Value)
+	}
+	for i := start; i < b.Len(); i++ {
+		if b.b[i] == '\n' || b.b[i] == '\r' {
+			b.b[i] = ' '
+		}
+	}
+	b.WriteByte('\n')
+	return nil
+}
+
+func (s *SyslogSink) Write(p []byte) (int, error) {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	return s.conn.write(p)
+}
+
+func (s *SyslogSink) Close() error {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	return s.conn.close()
+}
+
+// 头部字段不能为空或含空白，为空时使用 "-"
+func syslogHeaderField(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// 连接本机 syslog
+func dialLocalSyslog() (net.Conn, error) {
+	var err error
+	for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+		for _, network := range []string{"unixgram", "unix"} {
+			var conn net.Conn
+			if conn, err = net.DialTimeout(network, path, netDialTimeout); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, err
+}
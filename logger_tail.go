@@ -0,0 +1,253 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文本日志中的时间格式
+const recordTimeLayout = "2006/01/02 - 15:04:05.0000"
+
+var (
+	// 匹配终端颜色转义序列
+	ansiEscape = regexp.MustCompile("\033\\[[0-9;]*m")
+)
+
+// 解析后的日志记录
+type Record struct {
+	Level   LogType                // 日志级别
+	Time    time.Time              // 日志时间
+	Columns []string               // 数据列，文本日志按" | "切分，JSON日志为msg
+	Fields  map[string]interface{} // JSON日志的全部字段
+	Raw     string                 // 原始行(不含换行)
+}
+
+/*
+ * 解析一行日志，支持本包输出的文本格式(含颜色)与JSON格式
+ */
+func ParseRecord(line string) (Record, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	r := Record{Raw: line}
+
+	// JSON 格式
+	if strings.HasPrefix(line, "{") {
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			return r, false
+		}
+		r.Fields = fields
+		if level, ok := fields["level"].(string); ok {
+			t, err := ParseLogType(level)
+			if err != nil {
+				return r, false
+			}
+			r.Level = t
+		}
+		if ts, ok := fields["ts"].(string); ok {
+			r.Time, _ = time.Parse(time.RFC3339Nano, ts)
+		}
+		if msg, ok := fields["msg"].(string); ok {
+			r.Columns = []string{msg}
+		}
+		return r, true
+	}
+
+	// 文本格式: [ LEVEL ] 2006/01/02 - 15:04:05.0000 | a | b |
+	line = ansiEscape.ReplaceAllString(line, "")
+	if !strings.HasPrefix(line, "[") {
+		return r, false
+	}
+	end := strings.Index(line, "]")
+	if end < 0 {
+		return r, false
+	}
+	t, err := ParseLogType(line[1:end])
+	if err != nil {
+		return r, false
+	}
+	r.Level = t
+
+	rest := strings.TrimSpace(line[end+1:])
+	rest = strings.TrimSpace(strings.TrimSuffix(rest, "|"))
+	parts := strings.Split(rest, " | ")
+	r.Time, err = time.ParseInLocation(recordTimeLayout, parts[0], time.Local)
+	if err != nil {
+		return r, false
+	}
+	r.Columns = parts[1:]
+
+	return r, true
+}
+
+/*
+ * 跟踪日志文件并输出解析后的记录
+ *
+ * path 可以是普通文件、符号链接或通配模式(如 logs/*.log)：
+ * 符号链接指向新文件或模式匹配到更新的文件时，读完旧文件后自动切换；文件被截断时从头读取。
+ * fromEnd 为 true 时从当前文件末尾开始，只输出之后写入的日志。
+ */
+func Tail(path string, fromEnd bool) (*Tailer, error) {
+	t := &Tailer{
+		path:     path,
+		interval: 200 * time.Millisecond,
+		records:  make(chan Record, 1024),
+		done:     make(chan struct{}),
+	}
+
+	name, err := t.resolve()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.open(name, fromEnd); err != nil {
+		return nil, err
+	}
+
+	t.wg.Add(1)
+	go t.run()
+
+	return t, nil
+}
+
+// 日志跟踪器
+type Tailer struct {
+	path     string        // 跟踪的路径或模式
+	interval time.Duration // 轮询间隔
+	file     *os.File      // 正在读取的文件
+	info     os.FileInfo   // 正在读取的文件信息，用于判断是否轮转
+	reader   *bufio.Reader // 读缓冲
+	offset   int64         // 已读取的位置
+	partial  string        // 尚未读到换行的半行
+	records  chan Record   // 输出通道
+	done     chan struct{} // 停止信号
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// 解析后的日志记录，Stop 后通道关闭
+func (t *Tailer) Records() <-chan Record {
+	return t.records
+}
+
+// 停止跟踪
+func (t *Tailer) Stop() {
+	t.once.Do(func() {
+		close(t.done)
+		t.wg.Wait()
+		t.file.Close()
+		close(t.records)
+	})
+}
+
+// 计算当前应跟踪的文件
+func (t *Tailer) resolve() (string, error) {
+	if !strings.ContainsAny(t.path, "*?[") {
+		return t.path, nil
+	}
+
+	names, err := filepath.Glob(t.path)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", &os.PathError{Op: "tail", Path: t.path, Err: os.ErrNotExist}
+	}
+	// 轮转文件名按时间命名，取字典序最大的为当前文件
+	sort.Strings(names)
+	return names[len(names)-1], nil
+}
+
+// 打开文件
+func (t *Tailer) open(name string, fromEnd bool) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	offset := int64(0)
+	if fromEnd {
+		offset, err = file.Seek(0, io.SeekEnd)
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if t.file != nil {
+		t.file.Close()
+	}
+	t.file = file
+	t.info = info
+	t.offset = offset
+	t.partial = ""
+	t.reader = bufio.NewReader(file)
+	return nil
+}
+
+func (t *Tailer) run() {
+	defer t.wg.Done()
+
+	next := "" // 轮转后待切换的文件
+	for {
+		// 读取到文件末尾
+		for {
+			line, err := t.reader.ReadString('\n')
+			t.offset += int64(len(line))
+			if err != nil {
+				t.partial += line
+				break
+			}
+			line = t.partial + line
+			t.partial = ""
+			if r, ok := ParseRecord(line); ok {
+				select {
+				case t.records <- r:
+				case <-t.done:
+					return
+				}
+			}
+		}
+
+		// 旧文件已读完，切换到新文件
+		if next != "" {
+			t.open(next, false)
+			next = ""
+			continue
+		}
+
+		select {
+		case <-t.done:
+			return
+		case <-time.After(t.interval):
+		}
+
+		// 检查轮转和截断
+		name, err := t.resolve()
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if !os.SameFile(info, t.info) {
+			// 已轮转，先读完旧文件剩余内容再切换
+			next = name
+		} else if info.Size() < t.offset {
+			// 文件被截断，从头读取
+			t.open(name, false)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// 租户保留期限字段的键
+const RetentionKey = "retention"
+
+// 单个租户的策略
+type TenantPolicy struct {
+	Level      LogType       // 最低级别，只能比日志级别更严格
+	SampleRate float64       // 保留比例，如 0.1 表示每 10 条保留 1 条，0 或 1 表示全部保留
+	Retention  time.Duration // 保留期限，输出为 retention 字段供日志存储按租户清理，0 表示不输出
+}
+
+/*
+ * 按租户字段区分的策略
+ *
+ *   l.SetTenantPolicies(&logger.TenantPolicies{
+ *       Key:     "tenant",
+ *       Default: logger.TenantPolicy{Level: logger.INFO, Retention: 7 * 24 * time.Hour},
+ *       Tenants: map[string]logger.TenantPolicy{
+ *           "acme": {Level: logger.DEBUG, Retention: 90 * 24 * time.Hour},
+ *           "free": {Level: logger.WARN, SampleRate: 0.1},
+ *       },
+ *   })
+ *   l.With("tenant", "acme").Debug("...")
+ *
+ * 带租户字段的日志按其租户的策略过滤、采样并附加保留期限，未单独配置的租户使用 Default，
+ * 不带租户字段的日志不受影响。被采样丢弃的条数计入 Stats().Suppressed。
+ */
+type TenantPolicies struct {
+	Key     string                  // 租户字段名
+	Default TenantPolicy            // 未单独配置的租户的策略
+	Tenants map[string]TenantPolicy // 按租户ID的策略
+}
+
+// 租户策略与采样计数，由根日志的 l.mu 保护
+type tenantState struct {
+	policies TenantPolicies
+	counts   map[string]uint64 // 各租户的日志条数
+}
+
+func newTenantState(p TenantPolicies) *tenantState {
+	return &tenantState{policies: p, counts: map[string]uint64{}}
+}
+
+// 设置租户策略，为 nil 或 Key 为空时关闭。替换后采样重新计数
+// 派生日志设置根日志的策略，也可通过配置的 tenants 设置并随 ApplyConfig 热更新
+func (l *Logger) SetTenantPolicies(p *TenantPolicies) {
+	if l.noop() {
+		return
+	}
+
+	if l.root != nil {
+		l.root.SetTenantPolicies(p)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if p == nil || p.Key == "" {
+		l.tenants = nil
+		return
+	}
+	l.tenants = newTenantState(*p)
+}
+
+// 按租户策略处理一条日志，返回附加字段后的字段与处理结果
+func (t *tenantState) apply(level LogType, fields []Field) ([]Field, LogResult) {
+	tenant, ok := "", false
+	for _, f := range fields {
+		if f.Key == t.policies.Key {
+			tenant, ok = fmt.Sprint(f.Value), true
+			break
+		}
+	}
+	if !ok {
+		return fields, LogAccepted
+	}
+
+	policy, ok := t.policies.Tenants[tenant]
+	if !ok {
+		policy = t.policies.Default
+	}
+	if level < policy.Level {
+		return fields, LogFiltered
+	}
+	if rate := policy.SampleRate; rate > 0 && rate < 1 {
+		// 按比例均匀保留，第一条总是保留
+		n := t.counts[tenant] + 1
+		t.counts[tenant] = n
+		if math.Ceil(float64(n)*rate) == math.Ceil(float64(n-1)*rate) {
+			return fields, LogSampled
+		}
+	}
+	if policy.Retention > 0 {
+		fields = append(fields[:len(fields):len(fields)], Field{Key: RetentionKey, Value: policy.Retention.String()})
+	}
+	return fields, LogAccepted
+}
+
+// 将配置转为租户策略，Key 为空时返回 nil
+func tenantPolicies(c TenantConfig) (*TenantPolicies, error) {
+	if c.Key == "" {
+		return nil, nil
+	}
+	p := &TenantPolicies{Key: c.Key, Tenants: make(map[string]TenantPolicy, len(c.Policies))}
+	var err error
+	if p.Default, err = c.Default.policy(); err != nil {
+		return nil, err
+	}
+	for id, pc := range c.Policies {
+		if p.Tenants[id], err = pc.policy(); err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", id, err)
+		}
+	}
+	return p, nil
+}
+
+// 检查租户配置
+func validateTenants(c TenantConfig) []error {
+	var errs []error
+	if c.Key == "" && (len(c.Policies) > 0 || c.Default != TenantPolicyConfig{}) {
+		errs = append(errs, fmt.Errorf("logger: config tenants.key: is required"))
+	}
+	check := func(field string, pc TenantPolicyConfig) {
+		if _, err := pc.policy(); err != nil {
+			errs = append(errs, fmt.Errorf("logger: config tenants.%s: %v", field, err))
+		}
+	}
+	check("default", c.Default)
+	ids := make([]string, 0, len(c.Policies))
+	for id := range c.Policies {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		check(fmt.Sprintf("policies[%q]", id), c.Policies[id])
+	}
+	return errs
+}
+
+// 将单个租户的配置转为策略
+func (pc TenantPolicyConfig) policy() (TenantPolicy, error) {
+	p := TenantPolicy{SampleRate: pc.SampleRate}
+	if pc.Level != "" {
+		level, err := ParseLogType(pc.Level)
+		if err != nil {
+			return p, fmt.Errorf("unknown level %q", pc.Level)
+		}
+		p.Level = level
+	}
+	if pc.SampleRate < 0 || pc.SampleRate > 1 {
+		return p, fmt.Errorf("sample_rate %v is not between 0 and 1", pc.SampleRate)
+	}
+	retention, ok := parseConfigDuration(pc.Retention)
+	if !ok || retention < 0 {
+		return p, fmt.Errorf("invalid retention %q", pc.Retention)
+	}
+	p.Retention = retention
+	return p, nil
+}
@@ -4,7 +4,31 @@ package logger
 
 import (
 	// "log"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf16"
 
 	"github.com/whitewolfpipi/logger"
 )
@@ -41,3 +65,3175 @@ func BenchmarkLogger(b *testing.B) {
 	}
 
 }
+
+// ULID 在同一毫秒内也应严格递增
+func TestULIDGeneratorMonotonic(t *testing.T) {
+	g := logger.NewULIDGenerator()
+	prev := g.NewID()
+	for i := 0; i < 10000; i++ {
+		id := g.NewID()
+		if len(id) != 26 {
+			t.Fatalf("unexpected ulid length %d: %s", len(id), id)
+		}
+		if id <= prev {
+			t.Fatalf("ulid not monotonic: %s <= %s", id, prev)
+		}
+		prev = id
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// UUID 的版本号与 RFC 4122 变体位
+func TestUUIDGeneratorBits(t *testing.T) {
+	for version, g := range map[byte]logger.IDGenerator{'4': logger.NewUUIDv4Generator(), '7': logger.NewUUIDv7Generator()} {
+		for i := 0; i < 1000; i++ {
+			id := g.NewID()
+			if !uuidPattern.MatchString(id) || id[14] != version || !strings.ContainsRune("89ab", rune(id[19])) {
+				t.Fatalf("invalid uuidv%c: %s", version, id)
+			}
+		}
+	}
+}
+
+// UUIDv7 以毫秒时间戳开头，不同毫秒生成的ID按时间排序
+func TestUUIDv7TimeOrdered(t *testing.T) {
+	g := logger.NewUUIDv7Generator()
+	before := time.Now().UnixMilli()
+	prev := g.NewID()
+	ms, _ := strconv.ParseInt(strings.ReplaceAll(prev[:13], "-", ""), 16, 64)
+	if after := time.Now().UnixMilli(); ms < before || ms > after {
+		t.Fatalf("timestamp %d not in [%d, %d]", ms, before, after)
+	}
+	for i := 0; i < 5; i++ {
+		time.Sleep(2 * time.Millisecond)
+		id := g.NewID()
+		if id <= prev {
+			t.Fatalf("uuidv7 not time ordered: %s <= %s", id, prev)
+		}
+		prev = id
+	}
+}
+
+// snowflake 同一毫秒内序列号用尽后借用下一毫秒，ID 不重复且递增
+func TestSnowflakeSequenceWrap(t *testing.T) {
+	// epoch 晚于当前时间，时间戳不会前进，序列号必然在同一毫秒内回绕
+	g := logger.NewSnowflakeGenerator(5, time.Now().Add(time.Hour))
+	var prev int64 = -1
+	for i := 0; i < 3*4096; i++ {
+		id, err := strconv.ParseInt(g.NewID(), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= prev || id>>12&0x3ff != 5 {
+			t.Fatalf("id %d after %d (node %d)", id, prev, id>>12&0x3ff)
+		}
+		prev = id
+	}
+	if ms := prev >> 22; ms < 2 {
+		t.Fatalf("sequence wrapped %d times, want at least 2", ms)
+	}
+}
+
+// snowflake 节点号超出 0~1023 时 panic
+func TestSnowflakeNodeRange(t *testing.T) {
+	for _, node := range []int64{-1, 1024} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("node %d accepted", node)
+				}
+			}()
+			logger.NewSnowflakeGenerator(node, time.Time{})
+		}()
+	}
+	logger.NewSnowflakeGenerator(1023, time.Time{})
+}
+
+// Tail 跟随通配模式切换到轮转后的新文件
+func TestTailFollowsRotation(t *testing.T) {
+	dir := t.TempDir()
+	first := dir + "/2006-01-01.log"
+	if err := os.WriteFile(first, []byte("[ INFO     ] 2006/01/01 - 10:00:00.0000 | first |  \n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	tailer, err := logger.Tail(dir+"/*.log", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tailer.Stop()
+
+	r := <-tailer.Records()
+	if r.Level != logger.INFO || r.Columns[0] != "first" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+
+	second := dir + "/2006-01-02.log"
+	if err := os.WriteFile(second, []byte("[ ERROR    ] 2006/01/02 - 10:00:00.0000 | 404 | NOT FOUND! | \n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r = <-tailer.Records():
+	case <-time.After(2 * time.Second):
+		t.Fatal("tailer did not follow rotation")
+	}
+	if r.Level != logger.ERROR || len(r.Columns) != 2 || r.Columns[1] != "NOT FOUND!" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+}
+
+// JSON 编码器输出附加字段，与保留字段重名时加前缀
+func TestJSONEncoder(t *testing.T) {
+	enc := logger.NewJSONEncoder()
+	e := &logger.Entry{
+		Level:   logger.WARN,
+		Time:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		Message: "hello",
+		Fields:  []logger.Field{{Key: "user", Value: "bob"}, {Key: "msg", Value: 1}},
+	}
+	b, err := enc.Encode(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"schema_version":5,"level":"WARN","ts":"2006-01-02T15:04:05Z","msg":"hello","user":"bob","fields.msg":1}` + "\n"
+	if string(b) != want {
+		t.Fatalf("got %s want %s", b, want)
+	}
+}
+
+// 派生日志携带字段并经由根日志输出
+func TestWithFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.Start()
+
+	rl := l.With("request_id", "abc")
+	rl.Infow("done", "status", 200)
+	if err := l.Drain(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `"msg":"done","request_id":"abc","status":200`) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+// Close 写出全部待写日志，之后的日志不再输出
+func TestClose(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetCacheSwitch(false)
+	l.Start()
+
+	l.Info("before close")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after close")
+
+	out := buf.String()
+	if !strings.Contains(out, "before close") || strings.Contains(out, "after close") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+// 超过大小上限时切换文件并压缩旧文件
+func TestRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	l := logger.NewRotateFileLogger(dir, logger.WithMaxSize(100), logger.WithCompress(true), logger.WithMaxBackups(2))
+	l.SetCacheSwitch(false)
+	l.Start()
+	for i := 0; i < 10; i++ {
+		l.Info("0123456789012345678901234567890123456789")
+		l.Flush()
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, _ := filepath.Glob(dir + "/*.log.gz")
+	plain, _ := filepath.Glob(dir + "/*.log")
+	if len(gz) != 2 || len(plain) != 1 {
+		t.Fatalf("unexpected files: gz=%v plain=%v", gz, plain)
+	}
+}
+
+// 输出挂起时转储缓存并上报错误
+func TestFlushWatchdog(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+
+	l := logger.NewLogger()
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		<-hang
+		return len(p), nil
+	}))
+	l.SetFlushWatchdog(2)
+	errs := make(chan error, 1)
+	l.SetErrorHandler(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	l.Start()
+	l.Info("first") // 刷新时挂起
+	time.Sleep(200 * time.Millisecond)
+	l.Info("second") // 滞留在缓存中
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, logger.ErrFlushStalled) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("watchdog did not fire")
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// 附加输出端按各自级别和编码器输出
+func TestSinkLevels(t *testing.T) {
+	var all, errs bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&all)
+	l.AddSink(&logger.Sink{Writer: &errs, MinLevel: logger.ERROR, Encoder: logger.NewJSONEncoder()})
+	l.Start()
+
+	l.Info("info")
+	l.Error("error")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(all.String(), "info") || !strings.Contains(all.String(), "error") {
+		t.Fatalf("unexpected primary output: %s", all.String())
+	}
+	if strings.Contains(errs.String(), "info") || !strings.Contains(errs.String(), `"msg":"error"`) {
+		t.Fatalf("unexpected sink output: %s", errs.String())
+	}
+}
+
+// 超过有效期仍未写出的日志被丢弃
+func TestWithTTL(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+
+	l.WithTTL(time.Millisecond).Info("stale progress")
+	l.Info("kept")
+	time.Sleep(5 * time.Millisecond)
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "stale progress") || !strings.Contains(buf.String(), "kept") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+	if got := l.Stats().Expired; got != 1 {
+		t.Fatalf("expired = %d, want 1", got)
+	}
+}
+
+// 输出端故障期间日志压缩积压，恢复后按顺序写出
+func TestSinkBacklog(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	down := true
+	w := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return 0, errors.New("sink down")
+		}
+		return out.Write(p)
+	})
+
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetErrorHandler(func(error) {})
+	l.AddSink(&logger.Sink{Writer: w, CompressThreshold: 1024})
+
+	for i := 0; i < 200; i++ {
+		l.Info("entry " + strconv.Itoa(i))
+		l.Flush()
+	}
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 200 {
+		t.Fatalf("got %d lines, want 200", len(lines))
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, "entry "+strconv.Itoa(i)+" ") {
+			t.Fatalf("line %d out of order: %s", i, line)
+		}
+	}
+}
+
+// slog 经由日志输出，级别、属性与分组映射为级别和字段
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetLogLevel(logger.INFO)
+
+	s := slog.New(logger.NewSlogHandler(l)).With("app", "api").WithGroup("req")
+	s.Debug("hidden")
+	s.Warn("slow", "ms", 1200)
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("debug entry not filtered: %s", out)
+	}
+	for _, want := range []string{"WARN", "slow", "app=api", "req.ms=1200"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in %s", want, out)
+		}
+	}
+}
+
+// 主输出写入失败时返回错误并改写到备用输出
+func TestFallbackOutput(t *testing.T) {
+	var fallback bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		return 0, errors.New("disk full")
+	}))
+	l.SetFallbackOutput(&fallback)
+
+	l.Error("still logged")
+	if err := l.Flush(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("err = %v, want disk full", err)
+	}
+	if !strings.Contains(fallback.String(), "still logged") {
+		t.Fatalf("fallback output: %q", fallback.String())
+	}
+}
+
+// 输出端按允许/拒绝列表过滤字段
+func TestSinkFieldFilter(t *testing.T) {
+	var all, console, siem bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&all)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.AddSink(&logger.Sink{Writer: &console, AllowFields: []string{"request_id"}})
+	l.AddSink(&logger.Sink{Writer: &siem, DenyFields: []string{"token"}, Encoder: logger.NewJSONEncoder()})
+
+	l.Infow("login", "request_id", "r1", "user", "u1", "token", "secret")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(all.String(), "token=secret") {
+		t.Fatalf("primary output: %s", all.String())
+	}
+	if c := console.String(); !strings.Contains(c, "request_id=r1") || strings.Contains(c, "user") || strings.Contains(c, "token") {
+		t.Fatalf("console output: %s", c)
+	}
+	if s := siem.String(); !strings.Contains(s, `"user":"u1"`) || strings.Contains(s, "secret") {
+		t.Fatalf("siem output: %s", s)
+	}
+}
+
+// 调用位置指向日志方法的调用方
+func TestReportCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetReportCaller(true)
+
+	_, file, line, _ := runtime.Caller(0)
+	l.Info("direct")
+	l.With("k", "v").Infow("derived")
+	slog.New(logger.NewSlogHandler(l)).Info("slog")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines: %s", len(lines), buf.String())
+	}
+	for i, got := range lines {
+		want := filepath.Base(file) + ":" + strconv.Itoa(line+1+i) + " | "
+		if !strings.Contains(got, want) {
+			t.Fatalf("line %d: missing %q in %s", i, want, got)
+		}
+	}
+}
+
+// 管理接口输出状态并支持刷新和切换文件
+func TestAdminHandler(t *testing.T) {
+	dir := t.TempDir()
+	l := logger.NewRotateFileLogger(dir)
+	l.SetErrorHandler(func(error) {})
+	l.Start()
+	defer l.Close()
+	srv := httptest.NewServer(l.AdminHandler())
+	defer srv.Close()
+
+	l.Info("before rotate")
+	for _, path := range []string{"/flush", "/rotate"} {
+		resp, err := http.Post(srv.URL+path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d", path, resp.StatusCode)
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.log")); len(files) != 2 {
+		t.Fatalf("files after rotate: %v", files)
+	}
+
+	resp, err := http.Get(srv.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status struct {
+		Started bool
+		Config  struct{ Level string }
+		Stats   struct{ Entries int64 }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Started || status.Config.Level != "DEBUG" || status.Stats.Entries != 1 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+// 配置检查报告全部问题，合法配置无错误
+func TestValidateConfig(t *testing.T) {
+	ok := logger.Config{
+		Level:  "info",
+		Output: "file:///var/log/app",
+		Rotate: logger.RotateConfig{Interval: "24h", MaxBackups: 7, Compress: true},
+		Sinks:  []logger.SinkConfig{{URL: "stderr", Level: "ERROR", Encoding: "json"}},
+	}
+	if errs := logger.ValidateConfig(ok); errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	bad := logger.Config{
+		Level:  "verbose",
+		Rotate: logger.RotateConfig{Interval: "10s"},
+		Sinks:  []logger.SinkConfig{{URL: "kafka://broker/topic"}},
+	}
+	errs := logger.ValidateConfig(bad)
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	all := strings.Join(msgs, "\n")
+	for _, want := range []string{"level", "rotate.interval", "require a file output", "sinks[0].url"} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("missing %q in:\n%s", want, all)
+		}
+	}
+}
+
+// Printf 风格与多参数输出，被过滤的日志不格式化
+func TestPrintf(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetLogLevel(logger.INFO)
+
+	l.Debugf("hidden %v", stringerFunc(func() string {
+		t.Fatal("filtered entry was formatted")
+		return ""
+	}))
+	l.Infof("user %s logged in %d times", "bob", 3)
+	l.Warn("disk usage", 91, "%")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"user bob logged in 3 times", "disk usage91%"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in %s", want, out)
+		}
+	}
+}
+
+type stringerFunc func() string
+
+func (f stringerFunc) String() string {
+	return f()
+}
+
+// 队列满时按处理策略丢弃日志并计数
+func TestOverflowPolicy(t *testing.T) {
+	for _, policy := range []logger.OverflowPolicy{logger.OverflowDropNewest, logger.OverflowDropOldest} {
+		var out bytes.Buffer
+		gate := make(chan struct{})
+		l := logger.NewLogger()
+		l.SetEncoder(&logger.TextEncoder{})
+		l.SetCacheSwitch(false)
+		l.SetQueueSize(2)
+		l.SetOverflowPolicy(policy)
+		l.SetOutput(writerFunc(func(p []byte) (int, error) {
+			<-gate
+			return out.Write(p)
+		}))
+		var callbacks int64
+		l.SetDropHandler(func(string) { callbacks++ })
+		l.Start()
+
+		for i := 0; i < 10; i++ {
+			l.Info("entry " + strconv.Itoa(i))
+		}
+		close(gate)
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		written := strings.Count(out.String(), "\n")
+		dropped := l.Stats().Dropped
+		if dropped < 7 || int64(written)+dropped != 10 || callbacks != dropped {
+			t.Fatalf("%v: written %d dropped %d callbacks %d", policy, written, dropped, callbacks)
+		}
+		if policy == logger.OverflowDropOldest && !strings.Contains(out.String(), "entry 9 ") {
+			t.Fatalf("%v: newest entry lost: %s", policy, out.String())
+		}
+	}
+}
+
+// 未设置级别的配置不改变当前级别
+func TestApplyConfigKeepsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetLogLevel(logger.WARN)
+	if err := l.ApplyConfig(logger.Config{Encoding: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.GetLogLevel(); got != logger.WARN {
+		t.Fatalf("level reset to %v", got)
+	}
+	l.SetOutput(&buf)
+	l.Info("filtered")
+	l.Warn("kept")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); strings.Contains(out, "filtered") || !strings.Contains(out, `"msg":"kept"`) {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	if err := l.ApplyConfig(logger.Config{Level: "ERROR"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.GetLogLevel(); got != logger.ERROR {
+		t.Fatalf("level = %v, want ERROR", got)
+	}
+	l.Close()
+}
+
+// 运行中在缓存与队列间切换时，已缓存或排队的日志不丢失也不乱序
+func TestSetCacheSwitchRuntime(t *testing.T) {
+	var mu sync.Mutex
+	var out bytes.Buffer
+	l := logger.NewLogger()
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		time.Sleep(100 * time.Microsecond) // 写出较慢，切换时队列中仍有日志
+		mu.Lock()
+		defer mu.Unlock()
+		return out.Write(p)
+	}))
+	l.SetCacheDuration(60000) // 切换前日志都在缓存中
+	l.Start()
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		l.Info("entry " + strconv.Itoa(i))
+	}
+	l.SetCacheSwitch(false) // 缓存 -> 队列
+	for i := n; i < 2*n; i++ {
+		l.Info("entry " + strconv.Itoa(i))
+	}
+	l.SetCacheSwitch(true) // 队列 -> 缓存
+	for i := 2 * n; i < 3*n; i++ {
+		l.Info("entry " + strconv.Itoa(i))
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3*n {
+		t.Fatalf("got %d lines, want %d", len(lines), 3*n)
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, "| entry "+strconv.Itoa(i)+" |") {
+			t.Fatalf("line %d out of order: %s", i, line)
+		}
+	}
+}
+
+// 替换输出通路时并发输出的日志不丢失也不重复
+func TestApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg := func(name string) logger.Config {
+		return logger.Config{
+			Output: "file://" + filepath.Join(dir, name),
+			Sinks:  []logger.SinkConfig{{URL: "file://" + filepath.Join(dir, name+".errors"), Level: "ERROR", Encoding: "json"}},
+		}
+	}
+
+	l := logger.NewLogger()
+	if err := l.ApplyConfig(logger.Config{Level: "bogus"}); err == nil {
+		t.Fatal("invalid config applied")
+	}
+	if err := l.ApplyConfig(cfg("a")); err != nil {
+		t.Fatal(err)
+	}
+	l.Start()
+
+	const n = 1000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			l.Error("entry " + strconv.Itoa(i))
+		}
+	}()
+	for _, name := range []string{"b", "c", "d"} {
+		time.Sleep(time.Millisecond)
+		if err := l.ApplyConfig(cfg(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pattern := range []string{"*/*.log", "*.errors"} {
+		files, _ := filepath.Glob(filepath.Join(dir, pattern))
+		seen := map[string]int{}
+		for _, f := range files {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+				if i := strings.Index(line, "entry "); i >= 0 {
+					seen[strings.Fields(line[i+6:])[0]]++
+				}
+			}
+		}
+		if len(seen) != n {
+			t.Fatalf("%s: got %d distinct entries, want %d", pattern, len(seen), n)
+		}
+		for id, c := range seen {
+			if c != 1 {
+				t.Fatalf("%s: entry %s written %d times", pattern, id, c)
+			}
+		}
+	}
+}
+
+// 钩子只接收指定级别的日志，在日志锁之外执行
+func TestHook(t *testing.T) {
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	h := &recordHook{levels: []logger.LogType{logger.CRITICAL, logger.FATAL}, l: l}
+	l.AddHook(h)
+
+	l.Info("ignored")
+	l.With("code", 500).Critical("db down")
+	if len(h.entries) != 1 || h.entries[0].Message != "db down" || h.entries[0].Fields[0].Key != "code" {
+		t.Fatalf("hook entries: %+v", h.entries)
+	}
+}
+
+type recordHook struct {
+	levels  []logger.LogType
+	l       *logger.Logger
+	entries []*logger.Entry
+}
+
+func (h *recordHook) Levels() []logger.LogType {
+	return h.levels
+}
+
+func (h *recordHook) Fire(e *logger.Entry) error {
+	// 在锁内执行时会死锁
+	h.l.GetLogLevel()
+	h.entries = append(h.entries, e)
+	return nil
+}
+
+// 运行中通过 HTTP 调整级别
+func TestLevelHandler(t *testing.T) {
+	l := logger.NewLogger()
+	l.SetLogLevel(logger.INFO)
+	srv := httptest.NewServer(l.LevelHandler())
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(`{"level":"debug"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"level":"DEBUG"`) {
+		t.Fatalf("status %d body %s", resp.StatusCode, body)
+	}
+	if l.GetLogLevel() != logger.DEBUG {
+		t.Fatalf("level = %v, want DEBUG", l.GetLogLevel())
+	}
+
+	t.Setenv("TEST_LOG_LEVEL", "warn")
+	if err := l.SetLogLevelFromEnv("TEST_LOG_LEVEL"); err != nil || l.GetLogLevel() != logger.WARN {
+		t.Fatalf("level from env = %v, err %v", l.GetLogLevel(), err)
+	}
+}
+
+// 统计日志调用耗时并以 Prometheus 格式输出
+func TestCallLatency(t *testing.T) {
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetCallLatency(true)
+	for i := 0; i < 10; i++ {
+		l.With("i", i).Info("entry")
+	}
+
+	h := l.Stats().CallLatency
+	if h.Count != 10 || h.Sum <= 0 || h.Quantile(0.99) < h.Quantile(0.5) {
+		t.Fatalf("unexpected histogram: %+v", h)
+	}
+	var buf bytes.Buffer
+	if err := l.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`logger_call_duration_seconds_bucket{le="+Inf"} 10`, "logger_call_duration_seconds_count 10"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+// 重复日志按采样输出，关闭时输出抑制汇总
+func TestSampler(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetSampler(logger.SamplerConfig{Initial: 2, Thereafter: 5, Window: time.Hour})
+
+	for i := 0; i < 12; i++ {
+		l.Error("db timeout")
+	}
+	l.Info("other")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	// 第 1、2、7、12 条输出，其余 8 条抑制
+	if got := strings.Count(out, "| db timeout |"); got != 4 {
+		t.Fatalf("got %d db timeout lines:\n%s", got, out)
+	}
+	if !strings.Contains(out, "suppressed 8 similar messages: db timeout") || !strings.Contains(out, "other") {
+		t.Fatalf("missing summary:\n%s", out)
+	}
+	if got := l.Stats().Suppressed; got != 8 {
+		t.Fatalf("suppressed = %d, want 8", got)
+	}
+}
+
+// 严格模式下不支持的类型输出告警而不是静默丢弃
+func TestStrict(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetLoggerFormat(func(level logger.LogType, i interface{}) (string, []interface{}, bool) {
+		if s, ok := i.(string); ok {
+			return "%s\n", []interface{}{s}, true
+		}
+		return "", nil, true // 只支持字符串
+	})
+	l.Info([]string{"a", "b"})
+	l.SetStrict(true)
+	l.Info([]string{"c", "d"})
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if strings.Count(out, "\n") != 1 || !strings.Contains(out, "unsupported type []string") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+// 单个 error、数字等参数按 fmt.Sprint 输出，不再当作不支持的类型丢弃
+func TestSingleArgSprint(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.Start()
+
+	l.Info(errors.New("boom"))
+	l.Warn(42)
+	if r := l.Log(logger.ERROR, errors.New("failed")); !r.OK() {
+		t.Fatalf("log error: %v", r)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "| boom |") ||
+		!strings.Contains(lines[1], "| 42 |") || !strings.Contains(lines[2], "| failed |") {
+		t.Fatalf("unexpected output: %q", lines)
+	}
+}
+
+// gorm v1 的 SQL 日志转为字段输出
+func TestGormPrint(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+
+	l.Print("sql", "/app/user.go:12", 3*time.Millisecond, "SELECT * FROM users WHERE id = ?", []interface{}{1}, int64(1))
+	l.Print("log", "/app/user.go:20", "record not found")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"sql=SELECT * FROM users WHERE id = ?", "rows=1", "elapsed=3ms", "ERROR", "record not found"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+// 输出不是终端时默认去除颜色，ColorAlways 保留颜色
+func TestColorMode(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.Info([]string{"200-g", "ok"})
+	l.SetColorMode(logger.ColorAlways)
+	l.Info([]string{"500-r", "fail"})
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || strings.Contains(lines[0], "\033[") || !strings.Contains(lines[0], "200 | ok") {
+		t.Fatalf("color not stripped: %q", lines)
+	}
+	if !strings.Contains(lines[1], "\033[41;97m500\033[0m") {
+		t.Fatalf("color lost with ColorAlways: %q", lines[1])
+	}
+}
+
+// Log 返回日志是否被接受、过滤、采样或丢弃
+func TestLogResult(t *testing.T) {
+	gate := make(chan struct{})
+	l := logger.NewLogger()
+	l.SetCacheSwitch(false)
+	l.SetQueueSize(1)
+	l.SetOverflowPolicy(logger.OverflowDropNewest)
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		<-gate
+		return len(p), nil
+	}))
+	l.SetLogLevel(logger.INFO)
+	l.SetSampler(logger.SamplerConfig{Initial: 1, Window: time.Hour})
+	l.Start()
+
+	if r := l.Log(logger.DEBUG, "debug"); r != logger.LogFiltered {
+		t.Fatalf("debug: %v", r)
+	}
+	if r := l.Log(logger.INFO, "same", "k", 1); !r.OK() {
+		t.Fatalf("first: %v", r)
+	}
+	if r := l.Log(logger.INFO, "same", "k", 1); r != logger.LogSampled {
+		t.Fatalf("repeat: %v", r)
+	}
+	// 写出阻塞，队列满后新日志被丢弃
+	dropped := false
+	for i := 0; i < 10 && !dropped; i++ {
+		dropped = l.Log(logger.ERROR, "entry "+strconv.Itoa(i)) == logger.LogDropped
+	}
+	if !dropped {
+		t.Fatal("no entry reported as dropped")
+	}
+	close(gate)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if r := l.Log(logger.ERROR, "after close"); r != logger.LogClosed {
+		t.Fatalf("closed: %v", r)
+	}
+}
+
+type traceKey struct{}
+
+// context 中的字段与提取函数的结果附加到日志
+func TestContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetLogLevel(logger.INFO)
+	extracted := 0
+	l.SetContextExtractor(func(ctx context.Context) []logger.Field {
+		extracted++
+		if id, ok := ctx.Value(traceKey{}).(string); ok {
+			return []logger.Field{{Key: "trace_id", Value: id}}
+		}
+		return nil
+	})
+
+	ctx := logger.ContextWithFields(context.Background(), "request_id", "r1")
+	ctx = context.WithValue(ctx, traceKey{}, "t1")
+	l.InfoCtx(ctx, "handled")
+	l.DebugCtx(ctx, "filtered")
+	l.With("user", "u1").WithContext(ctx).Warn("slow")
+	slog.New(logger.NewSlogHandler(l)).InfoContext(ctx, "slog")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || extracted != 3 {
+		t.Fatalf("lines %d extracted %d:\n%s", len(lines), extracted, buf.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "request_id=r1") || !strings.Contains(line, "trace_id=t1") {
+			t.Fatalf("missing context fields: %s", line)
+		}
+	}
+	if !strings.Contains(lines[1], "user=u1") {
+		t.Fatalf("missing derived field: %s", lines[1])
+	}
+}
+
+type validationError struct{ field string }
+
+func (e *validationError) Error() string { return "invalid " + e.field }
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// 错误字段按注册的分类附加 error.kind
+func TestErrorKind(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.AddErrorKind("not_found", os.ErrNotExist)
+	l.AddErrorKind("validation", (*validationError)(nil))
+	l.AddErrorKind("timeout", (*interface{ Timeout() bool })(nil))
+
+	l.Errorw("open", "err", fmt.Errorf("load config: %w", os.ErrNotExist))
+	l.With("user", "u1").Errorw("save", "err", fmt.Errorf("save: %w", &validationError{"name"}))
+	l.Errorw("call", "err", timeoutError{})
+	l.Errorw("other", "err", errors.New("boom"))
+	l.Errorw("explicit", "err", os.ErrNotExist, logger.ErrorKindKey, "custom")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{"error.kind=not_found", "error.kind=validation", "error.kind=timeout", "", "error.kind=custom"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for i, w := range want {
+		if n := strings.Count(lines[i], "error.kind="); w == "" && n != 0 || w != "" && (n != 1 || !strings.Contains(lines[i], w)) {
+			t.Fatalf("line %d: want %q: %s", i, w, lines[i])
+		}
+	}
+}
+
+// nil 与零值日志的方法均为空操作，不会 panic
+func TestNilLogger(t *testing.T) {
+	var zero logger.Logger
+	for _, l := range []*logger.Logger{nil, &zero} {
+		l.SetLogLevel(logger.INFO)
+		l.SetOutput(io.Discard)
+		l.AddOutput(io.Discard, logger.DEBUG)
+		l.Start()
+		l.Info("info")
+		l.Errorf("%d", 1)
+		l.Warnw("warn", "k", 1)
+		l.InfoCtx(context.Background(), "ctx")
+		l.With("k", "v").WithTTL(time.Second).Info("child")
+		if r := l.Log(logger.ERROR, "log"); r.OK() {
+			t.Fatalf("nil logger accepted entry: %v", r)
+		}
+		if err := l.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if st := l.Stats(); st.Entries != 0 {
+			t.Fatalf("stats: %+v", st)
+		}
+	}
+}
+
+// 开启缓存时 Info 不产生堆内存分配
+func TestInfoZeroAlloc(t *testing.T) {
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.Start()
+	defer l.Close()
+
+	l.Info("warm up")
+	allocs := testing.AllocsPerRun(1000, func() {
+		l.Info("200 | ok! | GET /ping")
+	})
+	if allocs != 0 {
+		t.Fatalf("Info allocs/op = %v, want 0", allocs)
+	}
+}
+
+// 开启缓存时输出到丢弃输出的开销
+func BenchmarkInfoCache(b *testing.B) {
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.Start()
+	defer l.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("200 | ok! | GET /ping")
+	}
+}
+
+// 只实现分级方法的假日志
+type fakeLogger struct{ lines []string }
+
+func (f *fakeLogger) Debug(args ...interface{})  { f.lines = append(f.lines, fmt.Sprint(args...)) }
+func (f *fakeLogger) Info(args ...interface{})   { f.lines = append(f.lines, fmt.Sprint(args...)) }
+func (f *fakeLogger) Notice(args ...interface{}) { f.lines = append(f.lines, fmt.Sprint(args...)) }
+func (f *fakeLogger) Warn(args ...interface{})   { f.lines = append(f.lines, fmt.Sprint(args...)) }
+func (f *fakeLogger) Error(args ...interface{})  { f.lines = append(f.lines, fmt.Sprint(args...)) }
+func (f *fakeLogger) Fatal(args ...interface{})  { f.lines = append(f.lines, fmt.Sprint(args...)) }
+
+// 最小接口之外的能力通过类型断言探测
+func TestCapabilityInterfaces(t *testing.T) {
+	capabilities := func(l logger.ILogger) (flush, rotate, structured bool) {
+		_, flush = l.(logger.Flusher)
+		_, rotate = l.(logger.Rotator)
+		_, structured = l.(logger.Structured)
+		return
+	}
+
+	fake := &fakeLogger{}
+	fake.Info("ok")
+	if f, r, s := capabilities(fake); f || r || s || len(fake.lines) != 1 {
+		t.Fatalf("fake: flush %v rotate %v structured %v", f, r, s)
+	}
+	if f, r, s := capabilities(logger.NewLogger()); !f || r || !s {
+		t.Fatalf("Logger: flush %v rotate %v structured %v", f, r, s)
+	}
+	rl := logger.NewRotateFileLogger(t.TempDir())
+	defer rl.Close()
+	if f, r, s := capabilities(rl); !f || !r || !s {
+		t.Fatalf("RotateFileLogger: flush %v rotate %v structured %v", f, r, s)
+	}
+}
+
+// syslog 输出按 RFC 5424 格式发送，级别映射为严重性
+func TestSyslogSink(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+
+	w := logger.NewSyslogSink(logger.SyslogConfig{Network: "udp", Addr: pc.LocalAddr().String(), Facility: 16, Tag: "api", Hostname: "host1"})
+	defer w.Close()
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.AddOutput(w, logger.WARN)
+	l.Info("skipped")
+	l.Warnw("disk\nfull", "pct", 93)
+	l.Error("failed")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	want := []string{"<132>1 ", "<131>1 "} // local0(16)*8 + warning(4)/error(3)
+	for i, prefix := range want {
+		b := make([]byte, 2048)
+		n, _, err := pc.ReadFrom(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := string(b[:n])
+		if !strings.HasPrefix(msg, prefix) || !strings.Contains(msg, " host1 api ") {
+			t.Fatalf("message %d: %q", i, msg)
+		}
+		if i == 0 && !strings.HasSuffix(msg, " - - disk full pct=93") {
+			t.Fatalf("message %d: %q", i, msg)
+		}
+	}
+}
+
+// TCP 输出断开后自动重连，期间的日志在重连后补写
+func TestNetSinkReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // 先不监听，首次写出连接失败
+
+	w := logger.NewNetSink("tcp", addr)
+	defer w.Close()
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetErrorHandler(func(error) {})
+	l.AddOutput(w, logger.DEBUG)
+	defer l.Close()
+	l.Info("before")
+
+	time.Sleep(50 * time.Millisecond)
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	l.Info("after")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	for _, want := range []string{"before", "after"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil || m["msg"] != want {
+			t.Fatalf("want %q, got %q", want, line)
+		}
+	}
+}
+
+// ctx 取消时写出日志并关闭
+func TestStartContext(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	ctx, cancel := context.WithCancel(context.Background())
+	l.Start(ctx)
+	l.Info("before cancel")
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Log(logger.DEBUG, "closing") != logger.LogClosed {
+		if time.Now().After(deadline) {
+			t.Fatal("logger not closed after ctx cancelled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	l.Info("after close")
+	l.Close()
+	if out := buf.String(); !strings.Contains(out, "before cancel") || strings.Contains(out, "after close") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+// Close 合并全部输出端的错误并标注输出端
+func TestCloseJoinsErrors(t *testing.T) {
+	errDisk := errors.New("disk full")
+	errConn := errors.New("connection reset")
+	l := logger.NewLogger()
+	l.SetOutput(writerFunc(func(p []byte) (int, error) { return 0, errDisk }))
+	l.SetErrorHandler(func(error) {})
+	l.AddSink(&logger.Sink{Name: "siem", Writer: writerFunc(func(p []byte) (int, error) { return 0, errConn })})
+	l.AddSink(&logger.Sink{Name: "audit", Writer: writerFunc(func(p []byte) (int, error) { return 0, errConn })})
+	l.Info("hello")
+
+	err := l.Close()
+	if !errors.Is(err, errDisk) || !errors.Is(err, errConn) {
+		t.Fatalf("want both errors, got %v", err)
+	}
+	for _, name := range []string{"sink siem", "sink audit"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("missing %q in %v", name, err)
+		}
+	}
+}
+
+// 文件写入缓冲在切换和关闭时写出
+func TestRotateBufferSize(t *testing.T) {
+	dir := t.TempDir()
+	l := logger.NewRotateFileLogger(dir, logger.WithBufferSize(64<<10))
+	l.SetCacheSwitch(false)
+	l.Start()
+	for i := 0; i < 100; i++ {
+		l.Info("line " + strconv.Itoa(i))
+	}
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after rotate")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(dir + "/*.log")
+	var all string
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all += string(b)
+	}
+	if len(files) != 2 || !strings.Contains(all, "line 99") || !strings.Contains(all, "after rotate") {
+		t.Fatalf("unexpected files %v: %q", files, all)
+	}
+}
+
+// 时间切片按序号切换文件
+func TestRotateTimeSlice(t *testing.T) {
+	dir := t.TempDir()
+	l := logger.NewRotateFileLogger(dir, logger.WithTimeSlice(500*time.Millisecond))
+	l.SetCacheSwitch(false)
+	l.Start()
+	l.Info("phase one")
+	l.Flush()
+	time.Sleep(600 * time.Millisecond)
+	l.Info("phase two")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for seq, want := range map[string]string{"0001": "phase one", "0002": "phase two"} {
+		files, _ := filepath.Glob(dir + "/*." + seq + ".log")
+		if len(files) != 1 {
+			t.Fatalf("slice %s: files %v", seq, files)
+		}
+		b, _ := os.ReadFile(files[0])
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("slice %s: %q", seq, b)
+		}
+	}
+}
+
+// 关闭时输出运行汇总
+func TestShutdownSummary(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.SetShutdownSummary(true)
+	l.Info("a")
+	l.Info("b")
+	l.Error("c")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["msg"] != "logger summary" || m["entries"] != 3.0 || m["entries.info"] != 2.0 || m["entries.error"] != 1.0 {
+		t.Fatalf("unexpected summary %v", m)
+	}
+	if _, ok := m["duration_ms"]; !ok {
+		t.Fatalf("missing duration_ms in %v", m)
+	}
+	if st := l.Stats(); st.ByLevel[logger.INFO] != 3 {
+		t.Fatalf("unexpected stats %v", st.ByLevel)
+	}
+}
+
+// 级别高于 INFO 时仍输出运行汇总
+func TestShutdownSummaryAboveInfo(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.SetLogLevel(logger.WARN)
+	l.SetSampler(logger.SamplerConfig{Initial: 1, Window: time.Hour})
+	l.SetShutdownSummary(true)
+	l.Info("filtered")
+	l.Error("kept")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || m["msg"] != "logger summary" || m["level"] != "INFO" || m["entries"] != 1.0 {
+		t.Fatalf("unexpected output %q", lines)
+	}
+}
+
+// 切换后旧文件末尾写入标记行
+func TestRotateMarker(t *testing.T) {
+	dir := t.TempDir()
+	l := logger.NewRotateFileLogger(dir, logger.WithRotateMarker(true), logger.WithBufferSize(4096))
+	l.SetCacheSwitch(false)
+	l.Start()
+	l.Info("before rotate")
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after rotate")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(dir + "/*.log")
+	if len(files) != 2 {
+		t.Fatalf("unexpected files %v", files)
+	}
+	current := files[0] // 当前文件名不带切换时间，比备份短
+	if len(files[1]) < len(current) {
+		current = files[1]
+	}
+	for _, f := range files {
+		b, _ := os.ReadFile(f)
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		last := lines[len(lines)-1]
+		if f == current {
+			if strings.HasPrefix(last, logger.RotateMarker) {
+				t.Fatalf("unexpected marker in current file: %q", b)
+			}
+			continue
+		}
+		want := logger.RotateMarker + " next=" + filepath.Base(current) + " "
+		if !strings.HasPrefix(last, want) || !strings.Contains(string(b), "before rotate") {
+			t.Fatalf("missing marker in %s: %q", f, b)
+		}
+		if _, ok := logger.ParseRecord(last); ok {
+			t.Fatalf("marker parsed as record: %q", last)
+		}
+	}
+}
+
+// 来源字段输出在每条日志中，派生日志继承
+func TestSource(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	if err := l.SetSource("  "); err == nil {
+		t.Fatal("blank source accepted")
+	}
+	if err := l.SetSource("billing"); err != nil {
+		t.Fatal(err)
+	}
+	l.With("order", 1).Info("charged")
+	l.Flush()
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["source"] != "billing" || m["order"] != 1.0 {
+		t.Fatalf("unexpected entry %v", m)
+	}
+	if errs := logger.ValidateConfig(logger.Config{Source: " "}); len(errs) != 1 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+// 虚拟时钟，定时器由测试推进
+type fakeClock struct {
+	now  time.Time
+	tick chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time                          { return c.now }
+func (c *fakeClock) NewTicker(d time.Duration) logger.Ticker { return c }
+func (c *fakeClock) Chan() <-chan time.Time                  { return c.tick }
+func (c *fakeClock) Stop()                                   {}
+
+// 时间戳与缓存刷新使用设置的时间来源
+func TestClock(t *testing.T) {
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	clock := &fakeClock{now: time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC), tick: make(chan time.Time)}
+	l := logger.NewLogger()
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.Write(p)
+	}))
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.SetClock(clock)
+	l.Start()
+	defer l.Close()
+
+	l.Info("virtual")
+	clock.tick <- clock.now // 推进定时器，刷出缓存
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		out := buf.String()
+		mu.Unlock()
+		if out != "" {
+			if !strings.Contains(out, `"ts":"2001-02-03T04:05:06`) {
+				t.Fatalf("unexpected timestamp %q", out)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("tick did not flush cache")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 日志过期与积压重放的标记时间使用设置的时间来源
+func TestClockTTLAndReplay(t *testing.T) {
+	var buf bytes.Buffer
+	clock := &fakeClock{now: time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC), tick: make(chan time.Time)}
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetClock(clock)
+	l.WithTTL(time.Minute).Info("fresh")
+	l.Flush()
+	l.WithTTL(time.Minute).Info("stale")
+	clock.now = clock.now.Add(2 * time.Minute)
+	l.Flush()
+	if out := buf.String(); !strings.Contains(out, "fresh") || strings.Contains(out, "stale") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	var out bytes.Buffer
+	var mu sync.Mutex
+	down := true
+	w := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return 0, errors.New("sink down")
+		}
+		return out.Write(p)
+	})
+	l = logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetErrorHandler(func(error) {})
+	l.SetClock(clock)
+	l.AddSink(&logger.Sink{Writer: w, Encoder: logger.NewJSONEncoder()})
+	l.Info("during outage")
+	l.Flush()
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[logger.ReplayedKey] != true || !strings.HasPrefix(fmt.Sprint(m[logger.ReplayedAtKey]), "2001-02-03T04:07:06") {
+		t.Fatalf("unexpected replay mark %v", m)
+	}
+}
+
+// 访问日志文本按数据列输出，JSON 按字段输出
+func TestAccessEntry(t *testing.T) {
+	a := logger.AccessEntry{Status: 404, Method: "GET", Path: "/missing", Latency: 1500 * time.Microsecond, Size: 12, ClientIP: "10.0.0.1"}
+
+	var text bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&text)
+	l.SetColorMode(logger.ColorAlways)
+	l.SetEncoder(&logger.TextEncoder{Color: true})
+	if r := l.Access(a); !r.OK() {
+		t.Fatal(r)
+	}
+	l.Flush()
+	if out := text.String(); !strings.Contains(out, "WARN") || !strings.Contains(out, "404\033[0m | 1.5ms | 10.0.0.1 | \033[") || !strings.Contains(out, "/missing | 12B | ") {
+		t.Fatalf("unexpected text %q", out)
+	}
+
+	var js bytes.Buffer
+	l = logger.NewLogger()
+	l.SetOutput(&js)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.Access(a, logger.Field{Key: "request_id", Value: "r-1"})
+	l.Flush()
+	var m map[string]interface{}
+	if err := json.Unmarshal(js.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["msg"] != "GET /missing" || m["status"] != 404.0 || m["latency_ms"] != 1.5 || m["client_ip"] != "10.0.0.1" || m["request_id"] != "r-1" {
+		t.Fatalf("unexpected json %v", m)
+	}
+}
+
+// 状态码按类别、耗时按阈值着色
+func TestStatusLatencyTags(t *testing.T) {
+	cases := map[string]string{
+		logger.StatusTag(204):                     "204-g",
+		logger.StatusTag(302):                     "302",
+		logger.StatusTag(429):                     "429-y",
+		logger.StatusTag(503):                     "503-r",
+		logger.LatencyTag(time.Millisecond):       "1ms",
+		logger.LatencyTag(300 * time.Millisecond): "300ms-y",
+		logger.LatencyTag(2 * time.Second):        "2s-r",
+		logger.LatencyThresholds{}.Tag(time.Hour): "1h0m0s",
+	}
+	for got, want := range cases {
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+// 按租户的级别、采样与保留策略，随配置热更新
+func TestTenantPolicies(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	cfg := logger.Config{
+		Encoding: "json",
+		Tenants: logger.TenantConfig{
+			Key:     "tenant",
+			Default: logger.TenantPolicyConfig{Level: "WARN"},
+			Policies: map[string]logger.TenantPolicyConfig{
+				"acme": {Retention: "720h"},
+				"free": {SampleRate: 0.5},
+			},
+		},
+	}
+	if err := l.ApplyConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	l.SetOutput(&buf)
+
+	acme, free, other := l.With("tenant", "acme"), l.With("tenant", "free"), l.With("tenant", "other")
+	if r := acme.Log(logger.DEBUG, "acme debug"); !r.OK() {
+		t.Fatal(r)
+	}
+	if r := other.Log(logger.INFO, "other info"); r != logger.LogFiltered {
+		t.Fatal(r)
+	}
+	kept := 0
+	for i := 0; i < 10; i++ {
+		if free.Log(logger.INFO, "free").OK() {
+			kept++
+		}
+	}
+	if kept != 5 {
+		t.Fatalf("kept %d of 10", kept)
+	}
+	l.Info("no tenant")
+	l.Flush()
+	if out := buf.String(); !strings.Contains(out, `"retention":"720h0m0s"`) || !strings.Contains(out, "no tenant") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	// 热更新后策略立即生效
+	cfg.Tenants.Default.Level = ""
+	if err := l.ApplyConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if r := other.Log(logger.INFO, "other info"); !r.OK() {
+		t.Fatal(r)
+	}
+
+	bad := logger.Config{Tenants: logger.TenantConfig{Policies: map[string]logger.TenantPolicyConfig{"x": {SampleRate: 2}}}}
+	if errs := logger.ValidateConfig(bad); len(errs) != 2 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+// 按级别附加采样优先级，单条日志的优先级优先
+func TestLevelPriorities(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.SetLevelPriorities(map[logger.LogType]int{logger.ERROR: logger.PriorityKeep})
+	l.Error("failed")
+	l.Errorw("noise", logger.Priority(logger.PriorityDrop))
+	l.Info("ok")
+	l.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []interface{}{2.0, 0.0, nil}
+	for i, line := range lines {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m[logger.PriorityKey] != want[i] {
+			t.Fatalf("line %d: %v", i, m)
+		}
+	}
+}
+
+// 主输出与附加输出端按各自的方式分隔记录
+func TestSeparator(t *testing.T) {
+	var main, nul, length bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&main)
+	l.SetColorMode(logger.ColorNever)
+	l.SetSeparator(logger.SeparatorCRLF)
+	l.AddSink(&logger.Sink{Writer: &nul, Separator: logger.SeparatorNUL})
+	l.AddSink(&logger.Sink{Writer: &length, Separator: logger.SeparatorLength})
+	l.Info("a")
+	l.Info("b")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := main.String(); strings.Count(out, "\r\n") != 2 || !strings.HasSuffix(out, " | b | \r\n") {
+		t.Fatalf("unexpected main output %q", out)
+	}
+	if recs := strings.Split(strings.TrimSuffix(nul.String(), "\x00"), "\x00"); len(recs) != 2 || strings.Contains(nul.String(), "\n") {
+		t.Fatalf("unexpected nul output %q", nul.String())
+	}
+	p := length.Bytes()
+	for i := 0; i < 2; i++ {
+		n := int(p[0])<<24 | int(p[1])<<16 | int(p[2])<<8 | int(p[3])
+		if rec := string(p[4 : 4+n]); !strings.HasSuffix(rec, " | ") {
+			t.Fatalf("unexpected record %q", rec)
+		}
+		p = p[4+n:]
+	}
+	if len(p) != 0 {
+		t.Fatalf("trailing bytes %q", p)
+	}
+	if _, err := logger.ParseSeparator("tab"); err == nil {
+		t.Fatal("unknown separator accepted")
+	}
+}
+
+// 文件开头写入 BOM，UTF-16LE 写入时转码
+func TestFileEncoding(t *testing.T) {
+	utf16le := func(s string) string {
+		var b []byte
+		for _, r := range utf16.Encode([]rune(s)) {
+			b = append(b, byte(r), byte(r>>8))
+		}
+		return string(b)
+	}
+	for _, tc := range []struct {
+		enc    logger.FileEncoding
+		bom    string
+		decode func(string) string
+	}{
+		{logger.FileUTF8BOM, "\xEF\xBB\xBF", func(s string) string { return s }},
+		{logger.FileUTF16LE, "\xFF\xFE", utf16le},
+	} {
+		dir := t.TempDir()
+		l := logger.NewRotateFileLogger(dir, logger.WithFileEncoding(tc.enc), logger.WithRotateMarker(true))
+		l.Start()
+		l.Info("日志")
+		if err := l.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		l.Close()
+
+		files, _ := filepath.Glob(dir + "/*.log")
+		if len(files) != 2 {
+			t.Fatalf("%v: unexpected files %v", tc.enc, files)
+		}
+		for _, f := range files {
+			b, _ := os.ReadFile(f)
+			if !strings.HasPrefix(string(b), tc.bom) || strings.Count(string(b), tc.bom) != 1 {
+				t.Fatalf("%v: %s: % x", tc.enc, f, b)
+			}
+			if len(b) > len(tc.bom) && (!strings.Contains(string(b), tc.decode("| 日志 |")) || !strings.Contains(string(b), tc.decode(logger.RotateMarker))) {
+				t.Fatalf("%v: %s: % x", tc.enc, f, b)
+			}
+		}
+	}
+}
+
+// 故障注入：写出失败的日志留在积压中，批次内的顺序被打乱但不丢失
+func TestSinkChaos(t *testing.T) {
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetErrorHandler(func(error) {})
+	failing := &logger.Sink{Writer: io.Discard, Chaos: &logger.Chaos{Seed: 1, FailRate: 1}}
+	l.AddSink(failing)
+	l.Info("lost")
+	if err := l.Flush(); !errors.Is(err, logger.ErrChaos) {
+		t.Fatalf("flush error = %v, want ErrChaos", err)
+	}
+	if got := failing.ChaosStats().Failures; got == 0 {
+		t.Fatal("no failures injected")
+	}
+	l.RemoveSink(failing)
+
+	// 首次写出阻塞，其余日志积累为一批
+	var out bytes.Buffer
+	gate := make(chan struct{})
+	first := true
+	w := writerFunc(func(p []byte) (int, error) {
+		if first {
+			first = false
+			<-gate
+		}
+		return out.Write(p)
+	})
+	shuffled := &logger.Sink{Writer: w, Chaos: &logger.Chaos{Seed: 1, ReorderRate: 0.5}}
+	l.AddSink(shuffled)
+	l.Info("entry 0")
+	time.Sleep(10 * time.Millisecond)
+	for i := 1; i < 50; i++ {
+		l.Info("entry " + strconv.Itoa(i))
+	}
+	close(gate)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 50 {
+		t.Fatalf("got %d lines, want 50", len(lines))
+	}
+	if shuffled.ChaosStats().Reorders == 0 {
+		t.Fatal("no entries reordered")
+	}
+	for i := 0; i < 50; i++ {
+		if !strings.Contains(out.String(), "entry "+strconv.Itoa(i)+" ") {
+			t.Fatalf("entry %d missing: %s", i, out.String())
+		}
+	}
+}
+
+// 超过内存上限时同步写出，日志不丢失且保持顺序
+func TestMemoryLimit(t *testing.T) {
+	var out bytes.Buffer
+	var reported []error
+	l := logger.NewLogger()
+	l.SetOutput(&out)
+	l.SetErrorHandler(func(err error) { reported = append(reported, err) })
+	l.SetMemoryLimit(512)
+	l.SetCacheCap(1)
+	l.Start()
+
+	for i := 0; i < 50; i++ {
+		l.Info("entry " + strconv.Itoa(i))
+		if m := l.Stats().Memory; m > 512 {
+			t.Fatalf("memory = %d, limit 512", m)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reported) == 0 || !errors.Is(reported[0], logger.ErrMemoryLimit) {
+		t.Fatalf("reported = %v, want ErrMemoryLimit", reported)
+	}
+	if got := l.Stats().SyncWrites; got == 0 {
+		t.Fatal("no synchronous writes")
+	}
+	last := -1
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		i := strings.Index(line, "entry ")
+		n, _ := strconv.Atoi(strings.Fields(line[i+len("entry "):])[0])
+		if n != last+1 {
+			t.Fatalf("entry %d after %d:\n%s", n, last, out.String())
+		}
+		last = n
+	}
+	if last != 49 {
+		t.Fatalf("last entry = %d, want 49", last)
+	}
+}
+
+// 核心包只依赖标准库，第三方依赖只能出现在子包(如 gormlogger)中
+func TestCoreStdlibOnly(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range f.Imports {
+			path := strings.Trim(imp.Path.Value, `"`)
+			if first := strings.SplitN(path, "/", 2)[0]; strings.Contains(first, ".") {
+				t.Errorf("%s imports %s; move code with third-party dependencies to a subpackage", name, path)
+			}
+		}
+	}
+}
+
+// 启动时检查设置，无法运行的设置改用默认值并上报建议值
+func TestCheckSettings(t *testing.T) {
+	var reported []error
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetErrorHandler(func(err error) { reported = append(reported, err) })
+	l.SetCacheDuration(0)
+	l.Start()
+	l.Info("still running")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], logger.ErrBadSettings) ||
+		!strings.Contains(reported[0].Error(), "SetCacheDuration(100)") {
+		t.Fatalf("reported = %v", reported)
+	}
+
+	l = logger.NewLogger()
+	if errs := l.CheckSettings(); len(errs) != 0 {
+		t.Fatalf("defaults reported: %v", errs)
+	}
+	l.SetCacheSwitch(false)
+	l.SetQueueSize(0)
+	l.SetCacheDuration(100 * time.Millisecond)
+	errs := l.CheckSettings()
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "SetCacheDuration(100)") ||
+		!strings.Contains(errs[1].Error(), "SetQueueSize(1000)") {
+		t.Fatalf("errs = %v", errs)
+	}
+}
+
+// 启动前的日志在队列模式下也不阻塞，Start 时按顺序先写出
+func TestLogBeforeStart(t *testing.T) {
+	var out bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&out)
+	l.SetCacheSwitch(false)
+	done := make(chan struct{})
+	go func() {
+		l.Info("from init")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("logging before Start blocked")
+	}
+	l.Start()
+	l.Info("after start")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	first, second := strings.Index(out.String(), "from init"), strings.Index(out.String(), "after start")
+	if first < 0 || second < first {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+
+	// 未启动就关闭时写出
+	out.Reset()
+	l = logger.NewLogger()
+	l.SetOutput(&out)
+	l.Info("never started")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "never started") {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+}
+
+// 标准输出等文件已关闭时停用该输出并上报一次，不再重试
+func TestOutputClosed(t *testing.T) {
+	closedFile := func() *os.File {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+		w.Close()
+		return w
+	}
+
+	var fallback bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(closedFile())
+	l.SetFallbackOutput(&fallback)
+	l.SetErrorHandler(func(error) {})
+	sink := &logger.Sink{Writer: closedFile()}
+	l.AddSink(sink)
+
+	l.Info("first")
+	if err := l.Flush(); !errors.Is(err, logger.ErrOutputClosed) {
+		t.Fatalf("flush error = %v, want ErrOutputClosed", err)
+	}
+	l.Info("second")
+	if err := l.Flush(); err != nil {
+		t.Fatalf("disabled output reported again: %v", err)
+	}
+	if !strings.Contains(fallback.String(), "first") || !strings.Contains(fallback.String(), "second") {
+		t.Fatalf("fallback = %q", fallback.String())
+	}
+	if got := sink.Dropped(); got != 2 {
+		t.Fatalf("sink dropped = %d, want 2", got)
+	}
+}
+
+// 差异日志：文本按颜色列输出变更，JSON 输出 changes 数组
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{"level": "INFO", "rotate": map[string]interface{}{"max_size": 100, "compress": true}}
+	after := map[string]interface{}{"level": "DEBUG", "rotate": map[string]interface{}{"max_size": 100}, "source": "api"}
+	changes := logger.Changes(before, after)
+	want := []logger.Change{
+		{Key: "level", Op: logger.ChangeModified, Before: "INFO", After: "DEBUG"},
+		{Key: "rotate.compress", Op: logger.ChangeRemoved, Before: true},
+		{Key: "source", Op: logger.ChangeAdded, After: "api"},
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Fatalf("changes = %v, want %v", changes, want)
+	}
+
+	var text, js bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&text)
+	l.SetColorMode(logger.ColorAlways)
+	l.AddSink(&logger.Sink{Writer: &js, Encoder: logger.NewJSONEncoder()})
+	l.Diff("config reloaded", before, after, logger.Field{Key: "by", Value: "ops"})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"config reloaded", "~level: INFO -> DEBUG", "-rotate.compress=true", "+source=api", "\033[42;97m"} {
+		if !strings.Contains(text.String(), s) {
+			t.Fatalf("text output missing %q: %q", s, text.String())
+		}
+	}
+	var m struct {
+		Msg     string          `json:"msg"`
+		By      string          `json:"by"`
+		Changes []logger.Change `json:"changes"`
+	}
+	if err := json.Unmarshal(js.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Msg != "config reloaded" || m.By != "ops" || len(m.Changes) != 3 || m.Changes[0].After != "DEBUG" {
+		t.Fatalf("json output: %s", js.String())
+	}
+}
+
+// 进度按间隔输出，达到总数时输出完成
+func TestProgress(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC), tick: make(chan time.Time)}
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetClock(clock)
+	rec := &recordHook{levels: []logger.LogType{logger.INFO}, l: l}
+	l.AddHook(rec)
+
+	p := l.Progress("import", 1000)
+	for i := 0; i < 10; i++ {
+		clock.now = clock.now.Add(time.Second)
+		p.Add(50)
+	}
+	clock.now = clock.now.Add(10 * time.Second)
+	p.Add(500)
+	p.Add(1)
+	p.Done()
+
+	entries := rec.entries
+	field := func(e *logger.Entry, key string) interface{} {
+		for _, f := range e.Fields {
+			if f.Key == key {
+				return f.Value
+			}
+		}
+		return nil
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries: %v", len(entries), entries)
+	}
+	first := entries[0]
+	if first.Message != "import progress" || field(first, "done") != int64(250) ||
+		field(first, "percent") != 25.0 || field(first, "rate") != 50.0 || field(first, "eta_ms") != int64(15000) {
+		t.Fatalf("first progress: %+v", first)
+	}
+	if last := entries[2]; last.Message != "import done" || field(last, "done") != int64(1000) || field(last, "elapsed_ms") != int64(20000) {
+		t.Fatalf("done: %+v", last)
+	}
+}
+
+// 本地缓冲按批交给主输出，同一缓冲内保持顺序
+func TestLocalBuffers(t *testing.T) {
+	var out bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&out)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.SetLocalBuffers(1024, time.Hour)
+	l.Start()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			lb := l.With("g", g).NewLocalBuffer()
+			defer lb.Close()
+			for i := 0; i < 100; i++ {
+				lb.Infow("job", "i", i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	// 注销前未交接的缓冲由 Close 交接
+	l.NewLocalBuffer().Warnw("pending")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	next := map[int]int{}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	for _, line := range lines[:len(lines)-1] {
+		var m struct{ G, I int }
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.I != next[m.G] {
+			t.Fatalf("goroutine %d: entry %d after %d", m.G, m.I, next[m.G]-1)
+		}
+		next[m.G]++
+	}
+	if len(lines) != 801 || !strings.Contains(lines[800], "pending") {
+		t.Fatalf("got %d lines, last %q", len(lines), lines[len(lines)-1])
+	}
+	if got := l.Stats().Entries; got != 801 {
+		t.Fatalf("entries = %d, want 801", got)
+	}
+
+	// 未开启时等同于日志的方法
+	out.Reset()
+	l = logger.NewLogger()
+	l.SetOutput(&out)
+	l.NewLocalBuffer().Errorw("direct")
+	l.Flush()
+	if !strings.Contains(out.String(), "direct") {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+// 测试业务事件：不受日志级别与采样影响，按去向写入输出端
+func TestEvent(t *testing.T) {
+	var main, ops, analytics bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&main)
+	l.SetLogLevel(logger.ERROR)
+	l.SetSampler(logger.SamplerConfig{Initial: 1})
+	l.SetEventRoute(logger.EventsExclude)
+	l.AddSink(&logger.Sink{Writer: &ops, Events: logger.EventsExclude})
+	l.AddSink(&logger.Sink{Writer: &analytics, MinLevel: logger.ERROR, Events: logger.EventsOnly, Encoder: logger.NewJSONEncoder()})
+	l.Start()
+
+	l.Info("ignored")
+	for i := 0; i < 3; i++ {
+		// 结果只反映主输出，主输出不接收事件
+		if r := l.Event("order_paid", logger.Field{Key: "order", Value: i}); r != logger.LogFiltered {
+			t.Fatalf("Event = %v", r)
+		}
+	}
+	l.Error("payment failed")
+	l.SetEventLevel(logger.WARN)
+	if r := l.Event("order_paid"); r != logger.LogFiltered {
+		t.Fatalf("Event with WARN event level = %v", r)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, out := range map[string]string{"main": main.String(), "ops": ops.String()} {
+		if strings.Contains(out, "order_paid") || !strings.Contains(out, "payment failed") {
+			t.Fatalf("%s output: %q", name, out)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(analytics.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("analytics output: %q", analytics.String())
+	}
+	for i, line := range lines {
+		var m struct {
+			Msg   string `json:"msg"`
+			Event string `json:"event"`
+			Order int    `json:"order"`
+		}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.Msg != "order_paid" || m.Event != "order_paid" || m.Order != i {
+			t.Fatalf("event %d: %s", i, line)
+		}
+	}
+}
+
+// 测试严重性映射：内置映射、复制修改与 JSON 的 severity 字段
+func TestSeverityProfiles(t *testing.T) {
+	cases := []struct {
+		p      logger.SeverityProfile
+		level  logger.LogType
+		number int
+		text   string
+	}{
+		{logger.SyslogSeverities, logger.WARN, 4, "warning"},
+		{logger.GCPSeverities, logger.CRITICAL, 600, "CRITICAL"},
+		{logger.OTelSeverities, logger.NOTICE, 10, "INFO2"},
+		{logger.SystemdPriorities, logger.ERROR, 3, "<3>"},
+		{logger.OTelSeverities, logger.LogType(99), 21, "FATAL"},
+	}
+	for _, c := range cases {
+		if n, s := c.p.Number(c.level), c.p.Text(c.level); n != c.number || s != c.text {
+			t.Fatalf("%s %v = %d %q, want %d %q", c.p.Name, c.level, n, s, c.number, c.text)
+		}
+	}
+
+	p := logger.GCPSeverities.With(logger.FATAL, 800, "EMERGENCY")
+	if p.Text(logger.FATAL) != "EMERGENCY" || logger.GCPSeverities.Text(logger.FATAL) != "ALERT" {
+		t.Fatalf("With changed the built-in profile")
+	}
+
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.JSONEncoder{Severity: &p})
+	l.Warnw("disk almost full", "severity", "user")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["severity"] != "WARNING" || m["fields.severity"] != "user" {
+		t.Fatalf("json output: %s", buf.String())
+	}
+}
+
+// 测试调用链：按级别记录调用方起的多层位置
+func TestCallerChain(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.SetCallerChain(logger.ERROR, 2)
+
+	inner := func() { l.Error("query failed") }
+	outer := func() { inner() }
+	outer()
+	l.Info("no chain")
+	l.With(logger.Field{Key: "k", Value: 1}).Errorw("child")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output: %q", buf.String())
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &m); err != nil {
+		t.Fatal(err)
+	}
+	chain, _ := m[logger.CallerChainKey].(string)
+	if !regexp.MustCompile(`^logger_test\.go:\d+<logger_test\.go:\d+$`).MatchString(chain) {
+		t.Fatalf("chain = %q", chain)
+	}
+	if strings.Contains(lines[1], logger.CallerChainKey) || !strings.Contains(lines[2], logger.CallerChainKey) {
+		t.Fatalf("output: %q", buf.String())
+	}
+}
+
+// 测试共享回滚文件：多个日志写入同一文件，先关闭的一方不影响其他日志
+func TestSharedRotateFile(t *testing.T) {
+	dir := t.TempDir()
+	file := logger.NewRotateFileLogger(dir)
+	file.Start()
+	api := file.Facade("api")
+	api.SetLogLevel(logger.INFO)
+	api.Start()
+	db := file.Facade("db")
+	db.Start()
+
+	var wg sync.WaitGroup
+	for _, l := range []*logger.Logger{&file.Logger, api, db} {
+		wg.Add(1)
+		go func(l *logger.Logger) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				l.Infow("entry", "i", i)
+			}
+		}(l)
+	}
+	wg.Wait()
+	api.Debug("filtered")
+
+	// 文件自身与 api 先关闭，db 仍可写入
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db.Info("after close")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(files) != 1 {
+		t.Fatalf("files: %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 301 || strings.Contains(string(data), "filtered") || !strings.Contains(lines[300], "after close") {
+		t.Fatalf("%d lines, last %q", len(lines), lines[len(lines)-1])
+	}
+	if _, err := file.Share().Write([]byte("late\n")); !errors.Is(err, logger.ErrOutputClosed) {
+		t.Fatalf("write after close: %v", err)
+	}
+}
+
+// 自定义的可检查输出
+type preflightWriter struct {
+	bytes.Buffer
+	err error
+}
+
+func (w *preflightWriter) Preflight() error { return w.err }
+
+// 测试启动前检查：连接网络输出端、检查文件与自定义输出
+func TestPreflight(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := make(chan struct{})
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			close(accepted)
+			conn.Close()
+		}
+	}()
+	addr := ln.Addr().String()
+
+	file := logger.NewRotateFileLogger(t.TempDir())
+	defer file.Close()
+	net1 := logger.NewNetSink("tcp", addr)
+	defer net1.Close()
+	share := file.Share()
+	defer share.Close()
+	l := logger.NewLogger()
+	l.SetOutput(share)
+	l.AddOutput(net1, logger.INFO)
+	l.AddOutput(&preflightWriter{}, logger.INFO)
+	if err := l.Preflight(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("preflight did not dial")
+	}
+	if err := file.Preflight(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 关闭的端口、已关闭的文件与自定义输出的错误全部返回
+	ln.Close()
+	closed, err := os.CreateTemp(t.TempDir(), "closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	errAuth := errors.New("401 unauthorized")
+	bad := logger.NewLogger()
+	bad.SetOutput(closed)
+	bad.AddOutput(logger.NewNetSink("tcp", addr), logger.INFO)
+	bad.AddOutput(&preflightWriter{err: errAuth}, logger.INFO)
+	err = bad.Preflight()
+	if !errors.Is(err, os.ErrClosed) || !errors.Is(err, errAuth) || !strings.Contains(err.Error(), "dial tcp://"+addr) {
+		t.Fatalf("preflight error: %v", err)
+	}
+}
+
+// 编码 "bad" 时失败的编码器
+type failingEncoder struct{}
+
+func (failingEncoder) Encode(e *logger.Entry) ([]byte, error) {
+	if e.Message == "bad" {
+		return nil, errors.New("encode failed")
+	}
+	return []byte(e.Message + "\n"), nil
+}
+
+// 在持锁期间调用 fn，错误处理函数通过同一日志输出时不能死锁
+func withLoggingErrorHandler(t *testing.T, l *logger.Logger, fn func()) {
+	t.Helper()
+	l.SetErrorHandler(func(err error) { l.Warn("handled: " + err.Error()) })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock: error handler logs through the same logger")
+	}
+}
+
+// 测试错误处理函数可通过同一日志输出：编码失败与切换缓存时的写出失败在解锁后上报
+func TestErrorHandlerLogs(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(failingEncoder{})
+	withLoggingErrorHandler(t, l, func() { l.Info("bad") })
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "handled: encode failed") {
+		t.Fatalf("output: %q", buf.String())
+	}
+
+	// 写出失败后会重试一次，前两次写出均失败
+	var mu sync.Mutex
+	var out bytes.Buffer
+	failures := 0
+	l = logger.NewLogger()
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures < 2 {
+			failures++
+			return 0, errors.New("disk full")
+		}
+		return out.Write(p)
+	}))
+	l.Start()
+	l.Info("cached")
+	withLoggingErrorHandler(t, l, func() { l.SetCacheSwitch(false) })
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(out.String(), "handled: ") || !strings.Contains(out.String(), "disk full") {
+		t.Fatalf("output: %q", out.String())
+	}
+}
+
+// 测试启动时的设置问题在解锁后上报，错误处理函数可通过同一日志输出
+func TestStartReportsSettingsUnlocked(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetQueueSize(-1)
+	withLoggingErrorHandler(t, l, func() { l.Start() })
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "handled: ") {
+		t.Fatalf("output: %q", buf.String())
+	}
+}
+
+// 测试超出内存上限进入降级时在解锁后上报，错误处理函数可通过同一日志输出
+func TestMemoryLimitReportsUnlocked(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetCacheCap(1)
+	l.SetMemoryLimit(10)
+	l.Start()
+	withLoggingErrorHandler(t, l, func() { l.Info("over the limit") })
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "handled: ") || !strings.Contains(buf.String(), "over the limit") {
+		t.Fatalf("output: %q", buf.String())
+	}
+}
+
+// 测试保留策略只清理本日志的旧文件，目录中其他程序的日志不受影响
+func TestRetentionKeepsForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{"other-service.log", "other-service.log.gz", "notes.txt", "2020-01-01.log", "2020-01-02.20200102T030405.000.log.gz"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, old, old)
+	}
+
+	l := logger.NewRotateFileLogger(dir, logger.WithMaxBackups(1))
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"other-service.log", "other-service.log.gz", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("%s removed: %v", name, err)
+		}
+	}
+	// 本日志的两个旧文件只保留一个
+	own, _ := filepath.Glob(filepath.Join(dir, "2020-01-0*"))
+	if len(own) != 1 {
+		t.Fatalf("own backups: %v", own)
+	}
+}
+
+// 汇总读取 .gz 文件并返回无法解析的行数
+func TestSummarize(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "a.log")
+	lines := `{"level":"INFO","ts":"2024-01-02T03:04:05Z","msg":"hello 1"}
+{"level":"ERROR","ts":"2024-01-02T03:10:00Z","msg":"failed 2"}
+
+not a record
+` + logger.RotateMarker + " next=b.log\n"
+	if err := os.WriteFile(plain, []byte(lines), 0666); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "b.log.gz")
+	writeGzip(t, archive, `{"level":"INFO","ts":"2024-01-02T04:00:00Z","msg":"hello 3"}`+"\n")
+
+	summaries, err := logger.Summarize(plain, archive)
+	if !errors.Is(err, logger.ErrUnparsedLines) || !strings.Contains(err.Error(), "1 in "+plain) {
+		t.Fatalf("err: %v", err)
+	}
+	if len(summaries) != 2 || summaries[0].Total != 2 || summaries[0].Levels["ERROR"] != 1 || summaries[1].Total != 1 {
+		t.Fatalf("summaries: %+v", summaries)
+	}
+	if summaries[0].Templates[0].Template == "" || summaries[0].TopErrors[0].Template != "failed #" {
+		t.Fatalf("templates: %+v", summaries[0])
+	}
+}
+
+// 压缩归档只删除完整解析的文件，不处理当前文件
+func TestCompactFiles(t *testing.T) {
+	dir := t.TempDir()
+	record := `{"level":"INFO","ts":"2024-01-02T03:04:05Z","msg":"hello"}` + "\n"
+	good := filepath.Join(dir, "2020-01-01.log")
+	bad := filepath.Join(dir, "2020-01-02.log")
+	archive := filepath.Join(dir, "2020-01-03.log.gz")
+	os.WriteFile(good, []byte(record), 0666)
+	os.WriteFile(bad, []byte(record+"garbage\n"), 0666)
+	writeGzip(t, archive, record)
+	summary := filepath.Join(dir, "summary.jsonl")
+
+	l := logger.NewRotateFileLogger(dir)
+	l.Start()
+	defer l.Close()
+	l.Info("active")
+	l.Flush()
+
+	err := l.CompactFiles(summary, true)
+	if !errors.Is(err, logger.ErrUnparsedLines) {
+		t.Fatalf("err: %v", err)
+	}
+	for _, name := range []string{good, archive} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Fatalf("%s not removed: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(bad); err != nil {
+		t.Fatalf("partially parsed file removed: %v", err)
+	}
+	active, _ := filepath.Glob(filepath.Join(dir, time.Now().Format("2006-01-02")+"*"))
+	if len(active) != 1 {
+		t.Fatalf("active file: %v", active)
+	}
+
+	data, _ := os.ReadFile(summary)
+	var s logger.Summary
+	if err := json.Unmarshal(data, &s); err != nil || s.Total != 2 {
+		t.Fatalf("summary: %s %v", data, err)
+	}
+
+	// 通配模式可排除指定文件
+	os.WriteFile(good, []byte(record), 0666)
+	if err := logger.CompactFiles(filepath.Join(dir, "2020-*"), summary, true, bad); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(bad); err != nil {
+		t.Fatalf("excluded file removed: %v", err)
+	}
+}
+
+// 写入 gzip 文件
+func writeGzip(t *testing.T, path string, content string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte(content))
+	zw.Close()
+	f.Close()
+}
+
+// 来源字段与 gorm 等组件的 source 字段或日志自带的同名字段重名时，后者改名为 fields.source
+func TestSourceKeyCollision(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	l.SetSource("billing")
+	l.Infow("query", "source", "gorm/callbacks.go:42")
+	l.With(logger.SourceKey, "override").Info("derived")
+	l.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	for i, want := range []string{"gorm/callbacks.go:42", "override"} {
+		if n := strings.Count(lines[i], `"`+logger.SourceKey+`":`); n != 1 {
+			t.Fatalf("%d %s keys in %s", n, logger.SourceKey, lines[i])
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &m); err != nil || m[logger.SourceKey] != "billing" || m["fields.source"] != want {
+			t.Fatalf("unexpected entry %s %v", lines[i], err)
+		}
+	}
+}
+
+// 丢弃最旧日志时跳过业务事件，不改变队列中日志的顺序也不阻塞
+func TestOverflowDropOldestKeepsOrder(t *testing.T) {
+	var out bytes.Buffer
+	gate := make(chan struct{})
+	writing := make(chan struct{}, 1)
+	l := logger.NewLogger()
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetCacheSwitch(false)
+	l.SetQueueSize(2)
+	l.SetOverflowPolicy(logger.OverflowDropOldest)
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		select {
+		case writing <- struct{}{}:
+		default:
+		}
+		<-gate
+		return out.Write(p)
+	}))
+	l.Start()
+
+	l.Info("entry a")
+	<-writing // 第一条已取出并阻塞在写入中
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Event("paid")
+		l.Info("entry b")
+		l.Info("entry c") // 丢弃 b
+		l.Info("entry d") // 丢弃 c，事件保留在队首
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("enqueue blocked")
+	}
+	close(gate)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := regexp.MustCompile(`entry \w|\| paid`).FindAllString(out.String(), -1)
+	if strings.Join(got, ",") != "entry a,| paid,entry d" || l.Stats().Dropped != 2 {
+		t.Fatalf("unexpected output %q dropped %d", out.String(), l.Stats().Dropped)
+	}
+}
+
+// 输出端部分写出后失败，重试只写出剩余的内容
+func TestSinkBacklogPartialWrite(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	failed := false
+	w := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !failed {
+			failed = true
+			n, _ := out.Write(p[:len(p)/2])
+			return n, errors.New("short write")
+		}
+		return out.Write(p)
+	})
+
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetErrorHandler(func(error) {})
+	l.AddSink(&logger.Sink{Writer: w})
+	l.Info("first entry")
+	l.Flush()
+	l.Info("second entry")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || strings.Count(out.String(), "INFO") != 2 ||
+		!strings.Contains(lines[0], "first entry") || !strings.Contains(lines[1], "second entry") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+// 输出端写出卡住时状态接口仍可返回积压
+func TestAdminStatusStuckSink(t *testing.T) {
+	gate := make(chan struct{})
+	stuck := make(chan struct{})
+	var calls int32
+	w := writerFunc(func(p []byte) (int, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return 0, errors.New("sink down")
+		}
+		close(stuck)
+		<-gate
+		return len(p), nil
+	})
+
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetErrorHandler(func(error) {})
+	l.AddSink(&logger.Sink{Writer: w})
+	l.Start()
+	defer l.Close()
+	defer close(gate)
+
+	l.Info("first")
+	go l.Flush() // 首次写出失败积压，重试时卡住
+	<-stuck
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		l.AdminHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+		done <- rec
+	}()
+	select {
+	case rec := <-done:
+		var status struct {
+			Sinks []struct {
+				Healthy        bool
+				BacklogBatches int `json:"backlog_batches"`
+			}
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+		if len(status.Sinks) != 1 || status.Sinks[0].Healthy || status.Sinks[0].BacklogBatches == 0 {
+			t.Fatalf("unexpected status: %s", rec.Body.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("status blocked by stuck sink")
+	}
+}
+
+// 队列模式主输出卡住、日志调用阻塞在入队时状态接口仍可返回队列深度
+func TestAdminStatusStuckOutput(t *testing.T) {
+	gate := make(chan struct{})
+	stuck := make(chan struct{})
+	var once sync.Once
+	w := writerFunc(func(p []byte) (int, error) {
+		once.Do(func() { close(stuck) })
+		<-gate
+		return len(p), nil
+	})
+
+	l := logger.NewLogger()
+	l.SetOutput(w)
+	l.SetCacheSwitch(false)
+	l.SetQueueSize(1)
+	l.Start()
+	defer l.Close()
+	defer close(gate)
+
+	l.Info("first")
+	<-stuck
+	go func() {
+		for i := 0; i < 3; i++ {
+			l.Info("blocked") // 队列满后阻塞并持有锁
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		l.AdminHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+		done <- rec
+	}()
+	select {
+	case rec := <-done:
+		var status struct {
+			Busy  bool
+			Queue struct{ Queued int }
+			Stats struct{ Memory int64 }
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+		if !status.Busy || status.Queue.Queued != 1 || status.Stats.Memory == 0 {
+			t.Fatalf("unexpected status: %s", rec.Body.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("status blocked by stuck output")
+	}
+}
+
+// 文件关闭后切换返回 ErrOutputClosed，不再改名或创建文件；仍有共享时照常切换
+func TestRotateAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	file := logger.NewRotateFileLogger(dir)
+	file.Start()
+	shared := file.Facade("api")
+	shared.Start()
+	file.Info("entry")
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	shared.Info("shared")
+	if err := file.Rotate(); err != nil {
+		t.Fatalf("rotate with open share: %v", err)
+	}
+	if err := shared.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	before, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if err := file.Rotate(); !errors.Is(err, logger.ErrOutputClosed) {
+		t.Fatalf("rotate after close: %v", err)
+	}
+	after, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(before) != 2 || len(after) != len(before) {
+		t.Fatalf("files before %v after %v", before, after)
+	}
+}
+
+// 按大小切换改名失败时重新打开当前文件，之后的日志照常写入
+func TestRotateRenameFailure(t *testing.T) {
+	dir := t.TempDir()
+	l := logger.NewRotateFileLogger(dir)
+	l.SetErrorHandler(func(error) {})
+	l.Start()
+	defer l.Close()
+	l.Info("before")
+	l.Flush()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(files) != 1 {
+		t.Fatalf("files: %v", files)
+	}
+	os.Remove(files[0]) // 当前文件被外部删除，改名失败
+	if err := l.Rotate(); err == nil {
+		t.Fatal("rotate succeeded without current file")
+	}
+
+	l.Info("after")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil || !strings.Contains(string(data), "after") {
+		t.Fatalf("current file not reopened: %q %v", data, err)
+	}
+}
+
+// 停止监听级别信号的函数可重复调用
+func TestReloadLevelStopTwice(t *testing.T) {
+	l := logger.NewLogger()
+	stop := l.ReloadLevelOnSignal(func() (string, error) { return "INFO", nil })
+	stop()
+	stop()
+}
+
+// 派生日志的 SetEntryID 设置根日志，对根日志与派生日志都生效
+func TestSetEntryIDDerived(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(logger.NewJSONEncoder())
+	child := l.With("req", 1)
+	child.SetEntryID(true)
+	child.Info("child")
+	l.Info("root")
+	l.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	for _, line := range lines {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil || m["id"] == nil {
+			t.Fatalf("entry without id: %s %v", line, err)
+		}
+	}
+}
+
+// 各 schema 版本输出的字段固定不变，未知版本返回错误
+func TestJSONSchemaVersions(t *testing.T) {
+	e := &logger.Entry{
+		Level:   logger.WARN,
+		Time:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		Message: "hello",
+		ID:      "01J9Z3N5X8Q4W2K7R6T1M0V9CE",
+		Caller:  &logger.Caller{File: "/src/app/main.go", Line: 7, Function: "main.main"},
+		Fields:  []logger.Field{{Key: "user", Value: "bob"}, {Key: "severity", Value: 1}},
+	}
+	head := `"level":"WARN","ts":"2006-01-02T15:04:05Z"`
+	want := map[int]string{
+		1: `{"schema_version":1,` + head + `,"msg":"hello"}`,
+		2: `{"schema_version":2,` + head + `,"id":"01J9Z3N5X8Q4W2K7R6T1M0V9CE","msg":"hello"}`,
+		3: `{"schema_version":3,` + head + `,"id":"01J9Z3N5X8Q4W2K7R6T1M0V9CE","msg":"hello","user":"bob","severity":1}`,
+		4: `{"schema_version":4,` + head + `,"id":"01J9Z3N5X8Q4W2K7R6T1M0V9CE","caller":"app/main.go:7","func":"main.main","msg":"hello","user":"bob","severity":1}`,
+		5: `{"schema_version":5,"level":"WARN","severity":"WARNING","ts":"2006-01-02T15:04:05Z","id":"01J9Z3N5X8Q4W2K7R6T1M0V9CE","caller":"app/main.go:7","func":"main.main","msg":"hello","user":"bob","fields.severity":1}`,
+	}
+	for version := 1; version <= logger.SchemaVersion; version++ {
+		enc := &logger.JSONEncoder{SchemaVersion: version, Severity: &logger.GCPSeverities}
+		b, err := enc.Encode(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want[version]+"\n" {
+			t.Fatalf("version %d: got %s want %s", version, b, want[version])
+		}
+	}
+
+	enc := &logger.JSONEncoder{SchemaVersion: logger.SchemaVersion + 1}
+	if _, err := enc.Encode(e); err == nil || !strings.Contains(err.Error(), "unknown json schema version") {
+		t.Fatalf("unknown version: %v", err)
+	}
+}
+
+// 配额按字节数限制，每个窗口只写一次超额标记
+func TestQuotaWriterBytes(t *testing.T) {
+	var out bytes.Buffer
+	w := logger.NewQuotaWriter(&out, logger.Quota{Window: time.Hour, MaxBytes: 10})
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n"} {
+		if n, err := w.Write([]byte(line)); err != nil || n != len(line) {
+			t.Fatalf("write %q: %d %v", line, n, err)
+		}
+	}
+
+	got := out.String()
+	if !strings.HasPrefix(got, "aaaa\nbbbb\n") || strings.Contains(got, "cccc") || strings.Contains(got, "dddd") {
+		t.Fatalf("unexpected output %q", got)
+	}
+	if strings.Count(got, "quota exceeded") != 1 || !strings.Contains(got, "remaining entries dropped") {
+		t.Fatalf("marker: %q", got)
+	}
+	if w.Dropped() != 2 {
+		t.Fatalf("dropped %d", w.Dropped())
+	}
+}
+
+// 配额按条数限制，超额部分转写到 Divert
+func TestQuotaWriterDivert(t *testing.T) {
+	var out, divert bytes.Buffer
+	w := logger.NewQuotaWriter(&out, logger.Quota{Window: time.Hour, MaxEntries: 2, Divert: &divert})
+	if _, err := w.Write([]byte("1\n2\n3\n4\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(out.String(), "1\n2\n") || strings.Contains(out.String(), "3\n") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	lines := strings.Split(strings.TrimSpace(divert.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "remaining entries diverted") || lines[1] != "3" || lines[2] != "4" {
+		t.Fatalf("unexpected divert %q", divert.String())
+	}
+	if strings.Count(out.String(), "quota exceeded") != 1 || w.Dropped() != 2 {
+		t.Fatalf("marker %q dropped %d", out.String(), w.Dropped())
+	}
+}
+
+// 窗口结束后配额恢复，新窗口超额时再写一次标记
+func TestQuotaWriterWindowReset(t *testing.T) {
+	var out bytes.Buffer
+	w := logger.NewQuotaWriter(&out, logger.Quota{Window: 50 * time.Millisecond, MaxEntries: 1})
+	w.Write([]byte("a\n"))
+	w.Write([]byte("b\n"))
+	time.Sleep(60 * time.Millisecond)
+	w.Write([]byte("c\n"))
+	w.Write([]byte("d\n"))
+
+	got := out.String()
+	if !strings.Contains(got, "a\n") || !strings.Contains(got, "c\n") || strings.Contains(got, "b\n") || strings.Contains(got, "d\n") {
+		t.Fatalf("unexpected output %q", got)
+	}
+	if strings.Count(got, "quota exceeded") != 2 || w.Dropped() != 2 {
+		t.Fatalf("markers in %q dropped %d", got, w.Dropped())
+	}
+}
+
+// 管理器的组件日志共用根日志的输出，级别各自独立
+func TestManager(t *testing.T) {
+	var buf bytes.Buffer
+	m := logger.NewManager(nil)
+	m.Root().SetOutput(&buf)
+	m.Root().SetEncoder(logger.NewJSONEncoder())
+	m.Start()
+
+	db := m.Logger("db")
+	api := m.Logger("api")
+	if m.Logger("db") != db {
+		t.Fatal("logger not reused")
+	}
+	if names := m.Names(); strings.Join(names, ",") != "api,db" {
+		t.Fatalf("names: %v", names)
+	}
+
+	db.SetLogLevel(logger.WARN)
+	db.Info("db hidden")
+	db.Warn("db slow")
+	api.Info("api request")
+	if err := m.Root().Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "db hidden") {
+		t.Fatalf("per-name level ignored: %s", out)
+	}
+	for _, want := range []string{`"msg":"db slow","logger":"db"`, `"msg":"api request","logger":"api"`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %s in %s", want, out)
+		}
+	}
+
+	// 统一设置级别覆盖各组件日志
+	var all bytes.Buffer
+	m = logger.NewManager(nil)
+	m.Root().SetOutput(&all)
+	m.Start()
+	db = m.Logger("db")
+	m.SetLogLevel(logger.ERROR)
+	db.Warn("db warn")
+	m.Root().Warn("root warn")
+	db.Error("db error")
+	m.Root().Close()
+	if strings.Contains(all.String(), "warn") || !strings.Contains(all.String(), "db error") {
+		t.Fatalf("unexpected output %s", all.String())
+	}
+}
+
+// 超出写出延迟约定时计数并输出一条 WARN 日志
+func TestMaxLatency(t *testing.T) {
+	var mu sync.Mutex
+	var out bytes.Buffer
+	l := logger.NewLogger()
+	l.SetEncoder(&logger.TextEncoder{})
+	l.SetCacheSwitch(false)
+	l.SetMaxLatency(time.Nanosecond)
+	l.SetOutput(writerFunc(func(p []byte) (int, error) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		return out.Write(p)
+	}))
+	l.Start()
+	defer l.Close()
+
+	l.Info("slow entry")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := out.String()
+		mu.Unlock()
+		if strings.Contains(got, "write latency exceeded limit") {
+			if !strings.Contains(got, "WARN") || !strings.Contains(got, "limit=1ns") {
+				t.Fatalf("unexpected warning %q", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no latency warning: %q", got)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if v := l.Stats().LatencyViolations; v < 1 {
+		t.Fatalf("violations %d", v)
+	}
+}
+
+// 输出的条数与字节数按日志名称归属
+func TestStatsByName(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(&buf)
+	l.SetEncoder(&logger.TextEncoder{})
+	db := l.With()
+	db.SetName("db")
+	api := l.With()
+	api.SetName("api")
+
+	db.Info("db query")
+	db.Warn("db slow query")
+	api.Info("api request")
+	l.Info("root")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]logger.NameStats{}
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		name := ""
+		switch {
+		case line == "":
+			continue
+		case strings.Contains(line, "db "):
+			name = "db"
+		case strings.Contains(line, "api "):
+			name = "api"
+		}
+		ns := want[name]
+		ns.Entries++
+		ns.Bytes += int64(len(line))
+		want[name] = ns
+	}
+
+	st := l.Stats()
+	for _, name := range []string{"db", "api", ""} {
+		if st.ByName[name] != want[name] {
+			t.Fatalf("%q: got %+v want %+v", name, st.ByName[name], want[name])
+		}
+	}
+	if st.ByName["db"].Entries != 2 || st.Bytes != int64(buf.Len()) {
+		t.Fatalf("stats %+v output %d bytes", st, buf.Len())
+	}
+}
+
+// 级别前缀等长，带颜色时名称外包终端颜色，文本编码器输出以其开头
+func TestLevelPrefix(t *testing.T) {
+	width := len(logger.LevelPrefix(logger.DEBUG, false))
+	for level := logger.DEBUG; level <= logger.FATAL; level++ {
+		name := logger.GetLogTypeString(level)
+		plain := logger.LevelPrefix(level, false)
+		if plain != "[ "+name+" ] " || len(plain) != width {
+			t.Fatalf("%v: plain prefix %q", level, plain)
+		}
+		color := logger.LevelPrefix(level, true)
+		if !strings.HasPrefix(color, "[\033[") || !strings.HasSuffix(color, name+"\033[0m] ") {
+			t.Fatalf("%v: color prefix %q", level, color)
+		}
+		if strings.TrimSpace(regexp.MustCompile("\033\\[[0-9;]*m").ReplaceAllString(color, "")) != "["+name+"]" {
+			t.Fatalf("%v: color prefix %q", level, color)
+		}
+
+		for _, c := range []bool{false, true} {
+			b, err := (&logger.TextEncoder{Color: c}).Encode(&logger.Entry{Level: level, Time: time.Now(), Message: "x"})
+			if err != nil || !strings.HasPrefix(string(b), logger.LevelPrefix(level, c)) {
+				t.Fatalf("%v color=%v: %q %v", level, c, b, err)
+			}
+		}
+	}
+}
+
+// 故障恢复后补写的日志保留原有时间并带补发标记，正常写出的日志不带
+func TestSinkBacklogReplayed(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	down := true
+	w := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return 0, errors.New("sink down")
+		}
+		return out.Write(p)
+	})
+
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetErrorHandler(func(error) {})
+	l.AddSink(&logger.Sink{Writer: w, Encoder: logger.NewJSONEncoder()})
+	l.Info("during outage")
+	l.Flush()
+	l.Info("queued behind")
+	l.Flush()
+	captured := time.Now()
+
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	l.Flush()
+	l.Info("after recovery")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	for i, line := range lines {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("line %d: %s %v", i, line, err)
+		}
+		if i == 2 {
+			if _, ok := m[logger.ReplayedKey]; ok {
+				t.Fatalf("live entry marked: %s", line)
+			}
+			continue
+		}
+		ts, _ := time.Parse(time.RFC3339Nano, m["ts"].(string))
+		at, _ := time.Parse(time.RFC3339Nano, fmt.Sprint(m[logger.ReplayedAtKey]))
+		if m[logger.ReplayedKey] != true || ts.After(captured) || !at.After(captured) {
+			t.Fatalf("line %d not marked as replay: %s", i, line)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package logger
+
+import (
+	"io"
+	"os"
+)
+
+// 输出是否为终端
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0 && f.Name() != os.DevNull
+}
+
+// 非 Windows 终端直接支持颜色转义序列
+func enableVirtualTerminal(w io.Writer) bool {
+	return true
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// 写出停滞错误，可通过 errors.Is 判断
+var ErrFlushStalled = errors.New("logger: flusher stalled")
+
+// 设置写出停滞检查：有待写日志且连续 n 个刷新周期没有完成写出时(输出挂起、死锁)，
+// 调用错误处理函数并将缓存和队列中的日志同步转储到标准错误。n 为 0 时不检查
+func (l *Logger) SetFlushWatchdog(n int) {
+	if l.noop() {
+		return
+	}
+
+	atomic.StoreInt64(&l.stall.intervals, int64(n))
+}
+
+// 记录一次完成的写出
+func (l *Logger) markFlushed() {
+	atomic.StoreInt64(&l.stall.lastFlush, time.Now().UnixNano())
+}
+
+// 检查写出是否停滞
+func (l *Logger) checkStall() {
+	n := atomic.LoadInt64(&l.stall.intervals)
+	if n == 0 {
+		return
+	}
+
+	l.cache.mutex.Lock()
+	pending := len(l.cache.data) + len(l.queue)
+	l.cache.mutex.Unlock()
+	if pending == 0 {
+		return
+	}
+
+	limit := time.Duration(n) * time.Millisecond * l.cache.duration
+	last := atomic.LoadInt64(&l.stall.lastFlush)
+	stalled := time.Since(time.Unix(0, last))
+	if last == 0 || stalled <= limit {
+		return
+	}
+
+	dumped := l.dump(os.Stderr)
+	l.markFlushed() // 转储后重新计时，避免重复触发
+	l.handleError(fmt.Errorf("%w: no flush completed in %s, dumped %d entries to stderr",
+		ErrFlushStalled, stalled.Truncate(time.Millisecond), dumped))
+}
+
+// 紧急转储：绕过输出锁将缓存和队列中的日志直接写到 w，返回条数
+func (l *Logger) dump(w io.Writer) int {
+	// 停滞的写出可能仍持有交替使用的缓冲，此处换上新的缓冲
+	l.cache.mutex.Lock()
+	cached, cachedBuf := l.cache.data, l.cache.buf
+	l.cache.data = make([]queued, 0, l.cache.cacheCap)
+	l.cache.buf = &Buffer{}
+	l.cache.mutex.Unlock()
+
+	if p, _ := l.joinCached(cachedBuf, cached); len(p) > 0 {
+		w.Write(p)
+	}
+
+	pending := []queued{}
+loop:
+	for {
+		select {
+		case q := <-l.queue:
+			pending = append(pending, q)
+		default:
+			break loop
+		}
+	}
+
+	if buf, _ := l.joinQueued(pending); buf != nil {
+		w.Write(buf.Bytes())
+		buf.Free()
+	}
+	return len(cached) + len(pending)
+}
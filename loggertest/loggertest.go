@@ -0,0 +1,248 @@
+// 测试用的日志记录器，记录每次调用的级别、消息、字段与时间，并提供断言方法
+//
+//	rec := loggertest.New()
+//	svc := NewService(rec) // 依赖 logger.ILogger
+//	svc.Handle()
+//	rec.AssertLogged(t, logger.ERROR, "payment failed", "order", "o-1")
+package loggertest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/whitewolfpipi/logger"
+)
+
+// 一条记录的日志
+type Entry struct {
+	Level   logger.LogType
+	Message string
+	Fields  []logger.Field
+	Time    time.Time
+}
+
+// 按键获取字段值
+func (e Entry) Field(key string) (interface{}, bool) {
+	for _, f := range e.Fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (e Entry) String() string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(logger.GetLogTypeString(e.Level)))
+	b.WriteString(" ")
+	b.WriteString(e.Message)
+	for _, f := range e.Fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	return b.String()
+}
+
+// 记录日志调用的 ILogger 实现，可并发使用
+type Recorder struct {
+	mu      sync.Mutex
+	level   logger.LogType
+	entries []Entry
+}
+
+// 声明接口实现者
+var (
+	_ logger.StdLogger  = &Recorder{}
+	_ logger.Structured = &Recorder{}
+	_ logger.Flusher    = &Recorder{}
+)
+
+/*
+ * 创建记录器，默认记录全部级别
+ */
+func New() *Recorder {
+	return &Recorder{}
+}
+
+// 记录一条日志
+func (r *Recorder) record(level logger.LogType, msg string, fields []logger.Field) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if level < r.level {
+		return
+	}
+	r.entries = append(r.entries, Entry{Level: level, Message: msg, Fields: fields, Time: time.Now()})
+}
+
+// 已记录的日志
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// 指定级别的日志
+func (r *Recorder) Filter(level logger.LogType) []Entry {
+	entries := []Entry{}
+	for _, e := range r.Entries() {
+		if e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// 清空已记录的日志
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// 查找级别相同、消息包含 msg 且字段匹配 kvs 的日志
+func (r *Recorder) Find(level logger.LogType, msg string, kvs ...interface{}) (Entry, bool) {
+	for _, e := range r.Entries() {
+		if e.Level == level && strings.Contains(e.Message, msg) && matchFields(e, kvs) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// 断言有一条级别相同、消息包含 msg 且字段匹配 kvs 的日志
+func (r *Recorder) AssertLogged(t testing.TB, level logger.LogType, msg string, kvs ...interface{}) {
+	t.Helper()
+	if _, ok := r.Find(level, msg, kvs...); !ok {
+		t.Errorf("loggertest: no %s entry containing %q with fields %v; recorded:\n%s",
+			strings.TrimSpace(logger.GetLogTypeString(level)), msg, kvs, r.dump())
+	}
+}
+
+// 断言没有级别相同且消息包含 msg 的日志
+func (r *Recorder) AssertNotLogged(t testing.TB, level logger.LogType, msg string) {
+	t.Helper()
+	if e, ok := r.Find(level, msg); ok {
+		t.Errorf("loggertest: unexpected entry: %s", e)
+	}
+}
+
+// 断言记录的条数
+func (r *Recorder) AssertCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(r.Entries()); got != n {
+		t.Errorf("loggertest: recorded %d entries, want %d:\n%s", got, n, r.dump())
+	}
+}
+
+// 全部日志，每条一行，用于断言失败时输出
+func (r *Recorder) dump() string {
+	var b strings.Builder
+	for _, e := range r.Entries() {
+		b.WriteString("  ")
+		b.WriteString(e.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// 字段是否包含 kvs 中的全部键值对，值按 fmt.Sprint 比较
+func matchFields(e Entry, kvs []interface{}) bool {
+	for _, f := range toFields(kvs) {
+		v, ok := e.Field(f.Key)
+		if !ok || fmt.Sprint(v) != fmt.Sprint(f.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// 将 key, value 交替的参数转为字段，同 logger.Logger.With
+func toFields(kvs []interface{}) []logger.Field {
+	fields := make([]logger.Field, 0, (len(kvs)+1)/2)
+	for i := 0; i < len(kvs); i++ {
+		if f, ok := kvs[i].(logger.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		key := fmt.Sprint(kvs[i])
+		if i+1 >= len(kvs) {
+			fields = append(fields, logger.Field{Key: key, Value: "!MISSING"})
+			break
+		}
+		fields = append(fields, logger.Field{Key: key, Value: kvs[i+1]})
+		i++
+	}
+	return fields
+}
+
+// 日志方法的参数转为消息，切片以" | "拼接，多个参数同 fmt.Sprint
+func message(args []interface{}) string {
+	if len(args) == 1 {
+		switch v := args[0].(type) {
+		case string:
+			return v
+		case []string:
+			return strings.Join(v, " | ")
+		}
+	}
+	return fmt.Sprint(args...)
+}
+
+func (r *Recorder) Debug(args ...interface{})  { r.record(logger.DEBUG, message(args), nil) }
+func (r *Recorder) Info(args ...interface{})   { r.record(logger.INFO, message(args), nil) }
+func (r *Recorder) Notice(args ...interface{}) { r.record(logger.NOTICE, message(args), nil) }
+func (r *Recorder) Warn(args ...interface{})   { r.record(logger.WARN, message(args), nil) }
+func (r *Recorder) Error(args ...interface{})  { r.record(logger.ERROR, message(args), nil) }
+func (r *Recorder) Fatal(args ...interface{})  { r.record(logger.FATAL, message(args), nil) }
+
+func (r *Recorder) Debugw(msg string, kvs ...interface{}) {
+	r.record(logger.DEBUG, msg, toFields(kvs))
+}
+
+func (r *Recorder) Infow(msg string, kvs ...interface{}) {
+	r.record(logger.INFO, msg, toFields(kvs))
+}
+
+func (r *Recorder) Noticew(msg string, kvs ...interface{}) {
+	r.record(logger.NOTICE, msg, toFields(kvs))
+}
+
+func (r *Recorder) Warnw(msg string, kvs ...interface{}) {
+	r.record(logger.WARN, msg, toFields(kvs))
+}
+
+func (r *Recorder) Errorw(msg string, kvs ...interface{}) {
+	r.record(logger.ERROR, msg, toFields(kvs))
+}
+
+func (r *Recorder) Fatalw(msg string, kvs ...interface{}) {
+	r.record(logger.FATAL, msg, toFields(kvs))
+}
+
+// gorm v1 的日志以 INFO 记录
+func (r *Recorder) Print(args ...interface{}) {
+	r.record(logger.INFO, fmt.Sprint(args...), nil)
+}
+
+// 设置记录的最低级别
+func (r *Recorder) SetLogLevel(level logger.LogType) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.level = level
+}
+
+func (r *Recorder) GetLogLevel() logger.LogType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.level
+}
+
+// 记录器不格式化输出，忽略格式化函数
+func (r *Recorder) SetLoggerFormat(logger.FormatFunc) {}
+
+// 日志已同步记录，无需写出
+func (r *Recorder) Flush() error {
+	return nil
+}
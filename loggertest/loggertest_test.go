@@ -0,0 +1,57 @@
+package loggertest_test
+
+import (
+	"testing"
+
+	"github.com/whitewolfpipi/logger"
+	"github.com/whitewolfpipi/logger/loggertest"
+)
+
+// 依赖最小接口的被测代码
+func charge(l logger.ILogger, order string, ok bool) {
+	if s, isStructured := l.(logger.Structured); isStructured && !ok {
+		s.Errorw("payment failed", "order", order, "retry", 3)
+		return
+	}
+	l.Info("payment ok ", order)
+}
+
+func TestRecorder(t *testing.T) {
+	rec := loggertest.New()
+	charge(rec, "o-1", true)
+	charge(rec, "o-2", false)
+	rec.Info([]string{"200-g", "GET"})
+
+	rec.AssertCount(t, 3)
+	rec.AssertLogged(t, logger.INFO, "payment ok o-1")
+	rec.AssertLogged(t, logger.ERROR, "payment failed", "order", "o-2", "retry", 3)
+	rec.AssertLogged(t, logger.INFO, "200-g | GET")
+	rec.AssertNotLogged(t, logger.ERROR, "payment ok")
+	if e, ok := rec.Find(logger.ERROR, "payment"); !ok || e.Time.IsZero() {
+		t.Fatalf("entry: %+v", e)
+	}
+
+	// 断言失败时报告给测试
+	ft := &fakeT{}
+	rec.AssertLogged(ft, logger.ERROR, "payment failed", "order", "o-3")
+	if !ft.failed {
+		t.Fatal("mismatched field did not fail")
+	}
+
+	rec.Reset()
+	rec.SetLogLevel(logger.WARN)
+	rec.Info("filtered")
+	rec.Warn("kept")
+	if got := len(rec.Filter(logger.WARN)); got != 1 || len(rec.Entries()) != 1 {
+		t.Fatalf("entries after level filter: %v", rec.Entries())
+	}
+}
+
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(string, ...interface{}) { f.failed = true }
@@ -0,0 +1,184 @@
+// 第三方输出端的稳定接口，按语义化版本维护：同一主版本内只增加不修改，日志内部类型变化时由本包适配
+//
+//	type kafkaEncoder struct{}
+//
+//	func (kafkaEncoder) EncodeTo(b *sinkapi.Buffer, e sinkapi.Entry) error {
+//		b.WriteString(e.LevelName())
+//		b.WriteByte(' ')
+//		b.WriteString(e.Message())
+//		e.RangeFields(func(key string, value interface{}) bool {
+//			b.WriteByte(' ')
+//			b.WriteString(key)
+//			b.WriteByte('=')
+//			b.AppendValue(value)
+//			return true
+//		})
+//		b.WriteByte('\n')
+//		return nil
+//	}
+//
+//	l.AddSink(&logger.Sink{Writer: producer, Encoder: sinkapi.Adapt(kafkaEncoder{})})
+//
+// 输出端写出失败时，返回 Permanent 包装的错误表示重试无效(如消息被拒绝)，该批日志直接丢弃；
+// 其他错误的日志留在积压中稍后重试，见 logger.Sink。
+package sinkapi
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/whitewolfpipi/logger"
+)
+
+// 接口版本，主版本变化表示不兼容
+const Version = "1.0.0"
+
+// 日志条目的只读视图，编码器返回后不能再引用
+type Entry struct {
+	e *logger.Entry
+}
+
+// 由日志条目创建视图，供测试或自行适配编码器时使用
+func NewEntry(e *logger.Entry) Entry {
+	return Entry{e: e}
+}
+
+// 日志时间
+func (e Entry) Time() time.Time {
+	return e.e.Time
+}
+
+// 日志级别，数值越大越严重，见 logger.DEBUG 至 logger.FATAL
+func (e Entry) Level() int {
+	return int(e.e.Level)
+}
+
+// 日志级别名称，如 "INFO"
+func (e Entry) LevelName() string {
+	return strings.TrimSpace(logger.GetLogTypeString(e.e.Level))
+}
+
+// 日志条目ID，未开启时为空
+func (e Entry) ID() string {
+	return e.e.ID
+}
+
+// 日志内容，切片形式的日志以 " | " 拼接
+func (e Entry) Message() string {
+	return e.e.Text()
+}
+
+// 调用位置，未开启 SetReportCaller 时 ok 为 false
+func (e Entry) Caller() (file string, line int, function string, ok bool) {
+	if e.e.Caller == nil {
+		return "", 0, "", false
+	}
+	return e.e.Caller.File, e.e.Caller.Line, e.e.Caller.Function, true
+}
+
+// 按顺序遍历字段，fn 返回 false 时停止
+func (e Entry) RangeFields(fn func(key string, value interface{}) bool) {
+	for _, f := range e.e.AllFields() {
+		if !fn(f.Key, f.Value) {
+			return
+		}
+	}
+}
+
+// 按键查找字段值
+func (e Entry) Lookup(key string) (interface{}, bool) {
+	for _, f := range e.e.AllFields() {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// 编码缓冲，编码器返回后不能再引用
+type Buffer struct {
+	b *logger.Buffer
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	return b.b.Write(p)
+}
+
+func (b *Buffer) WriteString(s string) (int, error) {
+	return b.b.WriteString(s)
+}
+
+func (b *Buffer) WriteByte(c byte) error {
+	return b.b.WriteByte(c)
+}
+
+func (b *Buffer) AppendInt(i int64) {
+	b.b.AppendInt(i)
+}
+
+// 按 layout 追加时间，同 time.Time.AppendFormat
+func (b *Buffer) AppendTime(t time.Time, layout string) {
+	b.b.AppendTime(t, layout)
+}
+
+// 追加字段值，输出与 fmt.Sprint 一致
+func (b *Buffer) AppendValue(v interface{}) {
+	b.b.AppendValue(v)
+}
+
+func (b *Buffer) Len() int {
+	return b.b.Len()
+}
+
+// 第三方编码器，将一条日志编码到缓冲
+type Encoder interface {
+	EncodeTo(b *Buffer, e Entry) error
+}
+
+/*
+ * 将编码器适配为日志的编码器，用于 logger.Sink.Encoder
+ */
+func Adapt(enc Encoder) logger.BufferEncoder {
+	return adapter{enc: enc}
+}
+
+type adapter struct {
+	enc Encoder
+}
+
+func (a adapter) Encode(e *logger.Entry) ([]byte, error) {
+	buf := logger.GetBuffer()
+	defer buf.Free()
+	if err := a.EncodeTo(buf, e); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+func (a adapter) EncodeTo(buf *logger.Buffer, e *logger.Entry) error {
+	return a.enc.EncodeTo(&Buffer{b: buf}, Entry{e: e})
+}
+
+// 重试无效的错误
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string   { return p.err.Error() }
+func (p *permanentError) Unwrap() error   { return p.err }
+func (p *permanentError) Permanent() bool { return true }
+
+// 标记错误为重试无效，输出端返回后该批日志不再重试；err 为 nil 时返回 nil
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// 是否为重试无效的错误
+func IsPermanent(err error) bool {
+	var p interface{ Permanent() bool }
+	return errors.As(err, &p) && p.Permanent()
+}
@@ -0,0 +1,101 @@
+package sinkapi_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/whitewolfpipi/logger"
+	"github.com/whitewolfpipi/logger/sinkapi"
+)
+
+// 只依赖 sinkapi 的第三方编码器
+type lineEncoder struct{}
+
+func (lineEncoder) EncodeTo(b *sinkapi.Buffer, e sinkapi.Entry) error {
+	b.WriteString(e.LevelName())
+	b.WriteByte(' ')
+	b.WriteString(e.Message())
+	e.RangeFields(func(key string, value interface{}) bool {
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.AppendValue(value)
+		return true
+	})
+	b.WriteByte('\n')
+	return nil
+}
+
+func TestAdapt(t *testing.T) {
+	var out bytes.Buffer
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.AddSink(&logger.Sink{Writer: &out, Encoder: sinkapi.Adapt(lineEncoder{})})
+	l.Infow("order paid", "order", "o-1", "amount", 42)
+	l.Warn([]string{"200-g", "GET"})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "INFO order paid order=o-1 amount=42\nWARN 200 | GET\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	e := sinkapi.NewEntry(&logger.Entry{
+		Level:   logger.ERROR,
+		Message: "failed",
+		Fields:  []logger.Field{{Key: "order", Value: "o-1"}},
+	})
+	if e.Level() != int(logger.ERROR) || e.LevelName() != "ERROR" || e.Message() != "failed" {
+		t.Fatalf("entry = %d %s %s", e.Level(), e.LevelName(), e.Message())
+	}
+	if v, ok := e.Lookup("order"); !ok || v != "o-1" {
+		t.Fatalf("order = %v, %v", v, ok)
+	}
+	if _, _, _, ok := e.Caller(); ok {
+		t.Fatal("unexpected caller")
+	}
+}
+
+// 重试无效的错误不进入积压
+func TestPermanent(t *testing.T) {
+	rejected := errors.New("message rejected")
+	calls := 0
+	w := writerFunc(func(p []byte) (int, error) {
+		calls++
+		return 0, fmt.Errorf("produce: %w", sinkapi.Permanent(rejected))
+	})
+
+	l := logger.NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetErrorHandler(func(error) {})
+	s := &logger.Sink{Writer: w}
+	l.AddSink(s)
+	l.Info("a")
+	if err := l.Flush(); !sinkapi.IsPermanent(err) || !errors.Is(err, rejected) {
+		t.Fatalf("flush error = %v", err)
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatalf("batch retried: %v", err)
+	}
+	if calls != 1 || s.Dropped() != 1 {
+		t.Fatalf("calls = %d, dropped = %d", calls, s.Dropped())
+	}
+	if sinkapi.Permanent(nil) != nil || sinkapi.IsPermanent(rejected) {
+		t.Fatal("unexpected classification")
+	}
+	if !strings.HasPrefix(sinkapi.Version, "1.") {
+		t.Fatalf("version = %s", sinkapi.Version)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }